@@ -0,0 +1,356 @@
+package fabricgosdkclientcore
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	rwsetpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset"
+	kvrwsetpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	mspprotos "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+)
+
+//DecodedBlock is a block with its transactions unmarshalled
+type DecodedBlock struct {
+	Number       uint64                `json:"number"`
+	Hash         string                `json:"hash"`
+	DataHash     string                `json:"dataHash"`
+	PreviousHash string                `json:"previousHash"`
+	Transactions []*DecodedTransaction `json:"transactions"`
+}
+
+//DecodedTransaction is one transaction of a decoded block
+type DecodedTransaction struct {
+	Index              int                   `json:"index"`
+	TxID               string                `json:"txId"`
+	Type               string                `json:"type"`
+	ChannelID          string                `json:"channelId"`
+	Timestamp          time.Time             `json:"timestamp"`
+	CreatorMSPID       string                `json:"creatorMspId"`
+	CreatorSubject     string                `json:"creatorSubject,omitempty"`
+	ChaincodeName      string                `json:"chaincodeName,omitempty"`
+	ChaincodeVersion   string                `json:"chaincodeVersion,omitempty"`
+	Function           string                `json:"function,omitempty"`
+	Args               [][]byte              `json:"args,omitempty"`
+	ValidationCode     int32                 `json:"validationCode"`
+	ValidationCodeName string                `json:"validationCodeName"`
+	Endorsements       []*DecodedEndorsement `json:"endorsements,omitempty"`
+	ChaincodeResponse  *DecodedCCResponse    `json:"chaincodeResponse,omitempty"`
+	RWSets             []*NamespaceRWSet     `json:"rwSets,omitempty"`
+	ChaincodeEvent     *DecodedCCEvent       `json:"chaincodeEvent,omitempty"`
+	Config             *DecodedConfig        `json:"config,omitempty"`
+}
+
+//DecodedEndorsement is the endorsement of a peer on a transaction
+type DecodedEndorsement struct {
+	EndorserMSPID   string `json:"endorserMspId"`
+	EndorserSubject string `json:"endorserSubject,omitempty"`
+	Signature       []byte `json:"signature"`
+}
+
+//DecodedCCResponse is the response returned by the chain code during endorsement
+type DecodedCCResponse struct {
+	Status  int32  `json:"status"`
+	Message string `json:"message,omitempty"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+//NamespaceRWSet is the read/write set of a transaction for one chain code namespace
+type NamespaceRWSet struct {
+	Namespace string     `json:"namespace"`
+	Reads     []*KVRead  `json:"reads,omitempty"`
+	Writes    []*KVWrite `json:"writes,omitempty"`
+}
+
+//KVRead is a key read by a transaction with the version it read. A nil version means the key did not exist.
+type KVRead struct {
+	Key     string     `json:"key"`
+	Version *KVVersion `json:"version,omitempty"`
+}
+
+//KVVersion is the height of the transaction which wrote a key
+type KVVersion struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	TxNumber    uint64 `json:"txNumber"`
+}
+
+//KVWrite is a key written or deleted by a transaction
+type KVWrite struct {
+	Key      string `json:"key"`
+	Value    []byte `json:"value,omitempty"`
+	IsDelete bool   `json:"isDelete"`
+}
+
+//DecodedCCEvent is the chain code event emitted by a transaction
+type DecodedCCEvent struct {
+	ChaincodeID string `json:"chaincodeId"`
+	TxID        string `json:"txId"`
+	EventName   string `json:"eventName"`
+	Payload     []byte `json:"payload,omitempty"`
+}
+
+//IsValid returns true if the transaction was committed as valid
+func (dt *DecodedTransaction) IsValid() bool {
+	return dt.ValidationCode == int32(pb.TxValidationCode_VALID)
+}
+
+//DecodedBlockEventListener receives the decoded blocks of a block event subscription
+type DecodedBlockEventListener func(<-chan *DecodedBlock, *sync.WaitGroup)
+
+//DecodeBlock unmarshals the transactions of a block. The validation code of each transaction is
+//read from the transaction filter in the block metadata.
+func DecodeBlock(block *commonpb.Block) (*DecodedBlock, error) {
+	if block == nil || block.Header == nil {
+		return nil, fmt.Errorf("Block without header")
+	}
+	decoded := &DecodedBlock{
+		Number:       block.Header.Number,
+		Hash:         hex.EncodeToString(BlockHeaderHash(block.Header)),
+		DataHash:     hex.EncodeToString(block.Header.DataHash),
+		PreviousHash: hex.EncodeToString(block.Header.PreviousHash),
+		Transactions: make([]*DecodedTransaction, 0),
+	}
+	var txFilter []byte
+	if metadata := block.GetMetadata().GetMetadata(); len(metadata) > int(commonpb.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		txFilter = metadata[commonpb.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+	for index, envelopeBytes := range block.GetData().GetData() {
+		envelope := new(commonpb.Envelope)
+		if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+			return nil, fmt.Errorf("Invalid envelope %d in block %d: %v", index, decoded.Number, err)
+		}
+		code := int32(pb.TxValidationCode_NOT_VALIDATED)
+		if index < len(txFilter) {
+			code = int32(txFilter[index])
+		}
+		transaction, err := DecodeEnvelope(envelope, code)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to decode transaction %d in block %d: %v", index, decoded.Number, err)
+		}
+		transaction.Index = index
+		decoded.Transactions = append(decoded.Transactions, transaction)
+	}
+	return decoded, nil
+}
+
+//DecodeEnvelope unmarshals a transaction envelope. The validation code is not part of the
+//envelope and has to be passed in.
+func DecodeEnvelope(envelope *commonpb.Envelope, validationCode int32) (*DecodedTransaction, error) {
+	payload := new(commonpb.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, fmt.Errorf("Invalid payload: %v", err)
+	}
+	if payload.Header == nil {
+		return nil, fmt.Errorf("Payload without header")
+	}
+	channelHeader := new(commonpb.ChannelHeader)
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, channelHeader); err != nil {
+		return nil, fmt.Errorf("Invalid channel header: %v", err)
+	}
+	signatureHeader := new(commonpb.SignatureHeader)
+	if err := proto.Unmarshal(payload.Header.SignatureHeader, signatureHeader); err != nil {
+		return nil, fmt.Errorf("Invalid signature header: %v", err)
+	}
+	transaction := &DecodedTransaction{
+		TxID:               channelHeader.TxId,
+		Type:               commonpb.HeaderType(channelHeader.Type).String(),
+		ChannelID:          channelHeader.ChannelId,
+		ValidationCode:     validationCode,
+		ValidationCodeName: ValidationCodeName(validationCode),
+	}
+	if channelHeader.Timestamp != nil {
+		if timestamp, err := ptypes.Timestamp(channelHeader.Timestamp); err == nil {
+			transaction.Timestamp = timestamp
+		}
+	}
+	transaction.CreatorMSPID, transaction.CreatorSubject = decodeIdentity(signatureHeader.Creator)
+	switch channelHeader.Type {
+	case int32(commonpb.HeaderType_ENDORSER_TRANSACTION):
+		if err := decodeEndorserTransaction(payload.Data, transaction); err != nil {
+			return nil, err
+		}
+	case int32(commonpb.HeaderType_CONFIG):
+		config, err := decodeConfigTransaction(payload.Data)
+		if err != nil {
+			return nil, err
+		}
+		transaction.Config = config
+	}
+	return transaction, nil
+}
+
+//decodeIdentity returns the MSP ID and the certificate subject of a serialized identity
+func decodeIdentity(serializedIdentity []byte) (string, string) {
+	identity := new(mspprotos.SerializedIdentity)
+	if err := proto.Unmarshal(serializedIdentity, identity); err != nil {
+		return "", ""
+	}
+	certificate, err := parsePEMCertificate(identity.IdBytes)
+	if err != nil {
+		return identity.Mspid, ""
+	}
+	return identity.Mspid, certificate.Subject.String()
+}
+
+//parsePEMCertificate parses the first certificate of PEM encoded bytes
+func parsePEMCertificate(pemBytes []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM data found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func decodeEndorserTransaction(data []byte, transaction *DecodedTransaction) error {
+	tx := new(pb.Transaction)
+	if err := proto.Unmarshal(data, tx); err != nil {
+		return fmt.Errorf("Invalid transaction: %v", err)
+	}
+	for actionIndex, action := range tx.Actions {
+		actionPayload := new(pb.ChaincodeActionPayload)
+		if err := proto.Unmarshal(action.Payload, actionPayload); err != nil {
+			return fmt.Errorf("Invalid chaincode action payload: %v", err)
+		}
+		if actionIndex == 0 {
+			if err := decodeProposalPayload(actionPayload.ChaincodeProposalPayload, transaction); err != nil {
+				return err
+			}
+		}
+		if actionPayload.Action == nil {
+			continue
+		}
+		for _, endorsement := range actionPayload.Action.Endorsements {
+			mspID, subject := decodeIdentity(endorsement.Endorser)
+			transaction.Endorsements = append(transaction.Endorsements, &DecodedEndorsement{EndorserMSPID: mspID, EndorserSubject: subject, Signature: endorsement.Signature})
+		}
+		responsePayload := new(pb.ProposalResponsePayload)
+		if err := proto.Unmarshal(actionPayload.Action.ProposalResponsePayload, responsePayload); err != nil {
+			return fmt.Errorf("Invalid proposal response payload: %v", err)
+		}
+		chaincodeAction := new(pb.ChaincodeAction)
+		if err := proto.Unmarshal(responsePayload.Extension, chaincodeAction); err != nil {
+			return fmt.Errorf("Invalid chaincode action: %v", err)
+		}
+		if actionIndex == 0 && chaincodeAction.ChaincodeId != nil && len(chaincodeAction.ChaincodeId.Version) > 0 {
+			transaction.ChaincodeVersion = chaincodeAction.ChaincodeId.Version
+		}
+		if actionIndex == 0 && chaincodeAction.Response != nil {
+			transaction.ChaincodeResponse = &DecodedCCResponse{Status: chaincodeAction.Response.Status, Message: chaincodeAction.Response.Message, Payload: chaincodeAction.Response.Payload}
+		}
+		rwSets, err := decodeRWSets(chaincodeAction.Results)
+		if err != nil {
+			return err
+		}
+		transaction.RWSets = append(transaction.RWSets, rwSets...)
+		if len(chaincodeAction.Events) > 0 && transaction.ChaincodeEvent == nil {
+			ccEvent := new(pb.ChaincodeEvent)
+			if err := proto.Unmarshal(chaincodeAction.Events, ccEvent); err != nil {
+				return fmt.Errorf("Invalid chaincode event: %v", err)
+			}
+			if len(ccEvent.EventName) > 0 {
+				transaction.ChaincodeEvent = &DecodedCCEvent{ChaincodeID: ccEvent.ChaincodeId, TxID: ccEvent.TxId, EventName: ccEvent.EventName, Payload: ccEvent.Payload}
+			}
+		}
+	}
+	return nil
+}
+
+//decodeProposalPayload reads the chain code name, version, function and arguments of the proposal
+func decodeProposalPayload(proposalPayloadBytes []byte, transaction *DecodedTransaction) error {
+	proposalPayload := new(pb.ChaincodeProposalPayload)
+	if err := proto.Unmarshal(proposalPayloadBytes, proposalPayload); err != nil {
+		return fmt.Errorf("Invalid chaincode proposal payload: %v", err)
+	}
+	invocationSpec := new(pb.ChaincodeInvocationSpec)
+	if err := proto.Unmarshal(proposalPayload.Input, invocationSpec); err != nil {
+		return fmt.Errorf("Invalid chaincode invocation spec: %v", err)
+	}
+	spec := invocationSpec.ChaincodeSpec
+	if spec == nil {
+		return nil
+	}
+	if spec.ChaincodeId != nil {
+		transaction.ChaincodeName = spec.ChaincodeId.Name
+		transaction.ChaincodeVersion = spec.ChaincodeId.Version
+	}
+	if spec.Input != nil && len(spec.Input.Args) > 0 {
+		transaction.Function = string(spec.Input.Args[0])
+		transaction.Args = spec.Input.Args[1:]
+	}
+	return nil
+}
+
+//decodeRWSets unmarshals the key value read/write sets of a chain code action result
+func decodeRWSets(results []byte) ([]*NamespaceRWSet, error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+	txRWSet := new(rwsetpb.TxReadWriteSet)
+	if err := proto.Unmarshal(results, txRWSet); err != nil {
+		return nil, fmt.Errorf("Invalid read write set: %v", err)
+	}
+	rwSets := make([]*NamespaceRWSet, 0, len(txRWSet.NsRwset))
+	for _, nsRWSet := range txRWSet.NsRwset {
+		kvRWSet := new(kvrwsetpb.KVRWSet)
+		if err := proto.Unmarshal(nsRWSet.Rwset, kvRWSet); err != nil {
+			return nil, fmt.Errorf("Invalid key value read write set of %s: %v", nsRWSet.Namespace, err)
+		}
+		rwSet := &NamespaceRWSet{Namespace: nsRWSet.Namespace}
+		for _, read := range kvRWSet.Reads {
+			kvRead := &KVRead{Key: read.Key}
+			if read.Version != nil {
+				kvRead.Version = &KVVersion{BlockNumber: read.Version.BlockNum, TxNumber: read.Version.TxNum}
+			}
+			rwSet.Reads = append(rwSet.Reads, kvRead)
+		}
+		for _, write := range kvRWSet.Writes {
+			rwSet.Writes = append(rwSet.Writes, &KVWrite{Key: write.Key, Value: write.Value, IsDelete: write.IsDelete})
+		}
+		rwSets = append(rwSets, rwSet)
+	}
+	return rwSets, nil
+}
+
+//GetDecodedBlock returns a block of the channel decoded with DecodeBlock
+func (fsc *FabricSDKClient) GetDecodedBlock(channel string, blockNumber uint64) (*DecodedBlock, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	ledgerClient, err := fsc.getLedgerClient(channel)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ledgerClient.QueryBlock(blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("Error in retriving the block %d: %v", blockNumber, err)
+	}
+	return DecodeBlock(block)
+}
+
+//RegisterForDecodedBlockEvents register for block events delivered as decoded blocks. It uses the
+//block event subscription of the channel and user, DegisterBlockevent stops it.
+func (fsc *FabricSDKClient) RegisterForDecodedBlockEvents(channelID string, userID string, wg, wgListenr *sync.WaitGroup, eventLister DecodedBlockEventListener) bool {
+	decodingListener := func(blockEventChan <-chan *fab.BlockEvent, wgListenr *sync.WaitGroup) {
+		decodedBlockChan := make(chan *DecodedBlock)
+		defer close(decodedBlockChan)
+		go eventLister(decodedBlockChan, wgListenr)
+		for event := range blockEventChan {
+			decodedBlock, err := DecodeBlock(event.Block)
+			if err != nil {
+				_logger.Errorf("Unable to decode block event of channel %s: %+v", channelID, err)
+				continue
+			}
+			decodedBlockChan <- decodedBlock
+		}
+	}
+	return fsc.RegisterForBlockEvents(channelID, userID, wg, wgListenr, decodingListener)
+}
@@ -0,0 +1,321 @@
+package fabricgosdkclientcore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+)
+
+//Block export formats
+const (
+	//ExportFormatProtobuf writes each block as a uvarint length followed by the marshalled block
+	ExportFormatProtobuf = "protobuf"
+	//ExportFormatJSONL writes each block decoded with DecodeBlock as one JSON line
+	ExportFormatJSONL = "jsonl"
+)
+
+//ExportManifestFileName is the name of the manifest in the export directory
+const ExportManifestFileName = "manifest.json"
+
+//DefaultBlocksPerExportFile is the number of blocks written in one export file when none is configured
+const DefaultBlocksPerExportFile = 1000
+
+//BlockExportConfig configures a ledger export
+type BlockExportConfig struct {
+	Dir           string
+	Format        string
+	BlocksPerFile uint64
+	Concurrency   int
+}
+
+//ExportManifest describes the files of a ledger export. It is rewritten after each completed file,
+//so an interrupted export resumes after the last file listed.
+type ExportManifest struct {
+	Channel  string                `json:"channel"`
+	Format   string                `json:"format"`
+	From     uint64                `json:"from"`
+	To       uint64                `json:"to"`
+	Complete bool                  `json:"complete"`
+	Files    []*ExportManifestFile `json:"files"`
+}
+
+//ExportManifestFile is one file of a ledger export with its block range and hashes
+type ExportManifestFile struct {
+	Name           string `json:"name"`
+	FirstBlock     uint64 `json:"firstBlock"`
+	LastBlock      uint64 `json:"lastBlock"`
+	BlockCount     uint64 `json:"blockCount"`
+	FirstBlockHash string `json:"firstBlockHash"`
+	LastBlockHash  string `json:"lastBlockHash"`
+	SHA256         string `json:"sha256"`
+}
+
+//ExportLedger exports the blocks from block number from to block number to of the channel ledger.
+//An existing manifest of the same export in the directory is resumed.
+func (fsc *FabricSDKClient) ExportLedger(channel string, from, to uint64, config BlockExportConfig) (*ExportManifest, error) {
+	fetcher, err := fsc.ledgerBlockFetcher(channel)
+	if err != nil {
+		return nil, err
+	}
+	return ExportBlocks(fetcher, channel, from, to, config)
+}
+
+//ExportBlocks exports the blocks from block number from to block number to fetched by fetcher. The
+//blocks are exported backward when from is greater than to.
+func ExportBlocks(fetcher BlockFetcher, channel string, from, to uint64, config BlockExportConfig) (*ExportManifest, error) {
+	if config.Format != ExportFormatProtobuf && config.Format != ExportFormatJSONL {
+		return nil, fmt.Errorf("Unsupported export format %s", config.Format)
+	}
+	if config.BlocksPerFile == 0 {
+		config.BlocksPerFile = DefaultBlocksPerExportFile
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("Unable to create export directory %s: %v", config.Dir, err)
+	}
+	manifest, err := loadExportManifest(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		manifest = &ExportManifest{Channel: channel, Format: config.Format, From: from, To: to, Files: make([]*ExportManifestFile, 0)}
+	} else if manifest.Channel != channel || manifest.Format != config.Format || manifest.From != from || manifest.To != to {
+		return nil, fmt.Errorf("Export directory %s holds a different export of %s blocks %d to %d as %s", config.Dir, manifest.Channel, manifest.From, manifest.To, manifest.Format)
+	}
+	if manifest.Complete {
+		return manifest, nil
+	}
+	resumeFrom := from
+	if fileCount := len(manifest.Files); fileCount > 0 {
+		lastBlock := manifest.Files[fileCount-1].LastBlock
+		if from <= to {
+			resumeFrom = lastBlock + 1
+		} else {
+			resumeFrom = lastBlock - 1
+		}
+		_logger.Infof("Resuming export of %s from block %d", channel, resumeFrom)
+	}
+	iterator := NewBlockIterator(fetcher, resumeFrom, to, config.Concurrency)
+	defer iterator.Close()
+	for remaining := iterator.Len(); remaining > 0; {
+		count := config.BlocksPerFile
+		if remaining < count {
+			count = remaining
+		}
+		exportFile, err := writeExportFile(iterator, config, count)
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Files = append(manifest.Files, exportFile)
+		remaining -= count
+		manifest.Complete = remaining == 0
+		if err := saveExportManifest(config.Dir, manifest); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, nil
+}
+
+//writeExportFile writes the next count blocks of the iterator in a new export file. The file is
+//written under a temporary name and renamed once complete.
+func writeExportFile(iterator *BlockIterator, config BlockExportConfig, count uint64) (*ExportManifestFile, error) {
+	tmpPath := filepath.Join(config.Dir, "export.tmp")
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create export file: %v", err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	writer := bufio.NewWriter(io.MultiWriter(file, hash))
+	exportFile := &ExportManifestFile{BlockCount: count}
+	for index := uint64(0); index < count; index++ {
+		block, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if err := writeExportBlock(writer, config.Format, block); err != nil {
+			return nil, fmt.Errorf("Unable to export block %d: %v", block.GetHeader().GetNumber(), err)
+		}
+		blockHash := hex.EncodeToString(BlockHeaderHash(block.Header))
+		if index == 0 {
+			exportFile.FirstBlock = block.Header.Number
+			exportFile.FirstBlockHash = blockHash
+		}
+		exportFile.LastBlock = block.Header.Number
+		exportFile.LastBlockHash = blockHash
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("Unable to write export file: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("Unable to write export file: %v", err)
+	}
+	exportFile.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	extension := ".pb"
+	if config.Format == ExportFormatJSONL {
+		extension = ".jsonl"
+	}
+	exportFile.Name = fmt.Sprintf("blocks-%d-%d%s", exportFile.FirstBlock, exportFile.LastBlock, extension)
+	if err := os.Rename(tmpPath, filepath.Join(config.Dir, exportFile.Name)); err != nil {
+		return nil, fmt.Errorf("Unable to rename export file: %v", err)
+	}
+	return exportFile, nil
+}
+
+func writeExportBlock(writer io.Writer, format string, block *commonpb.Block) error {
+	if block == nil || block.Header == nil {
+		return fmt.Errorf("Block without header")
+	}
+	if format == ExportFormatJSONL {
+		decoded, err := DecodeBlock(block)
+		if err != nil {
+			return err
+		}
+		line, err := json.Marshal(decoded)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(append(line, '\n'))
+		return err
+	}
+	blockBytes, err := proto.Marshal(block)
+	if err != nil {
+		return err
+	}
+	lengthBytes := make([]byte, binary.MaxVarintLen64)
+	if _, err := writer.Write(lengthBytes[:binary.PutUvarint(lengthBytes, uint64(len(blockBytes)))]); err != nil {
+		return err
+	}
+	_, err = writer.Write(blockBytes)
+	return err
+}
+
+func loadExportManifest(dir string) (*ExportManifest, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, ExportManifestFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read export manifest: %v", err)
+	}
+	manifest := new(ExportManifest)
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("Invalid export manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+func saveExportManifest(dir string, manifest *ExportManifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, ExportManifestFileName), content); err != nil {
+		return fmt.Errorf("Unable to save export manifest: %v", err)
+	}
+	return nil
+}
+
+//ReadExportManifest reads the manifest of an export directory
+func ReadExportManifest(dir string) (*ExportManifest, error) {
+	manifest, err := loadExportManifest(dir)
+	if err == nil && manifest == nil {
+		err = fmt.Errorf("No export manifest in %s", dir)
+	}
+	return manifest, err
+}
+
+//ReadExportedBlocks calls handler with each block of a protobuf export in the manifest order. The
+//hash of each file is checked against the manifest before its blocks are read.
+func ReadExportedBlocks(dir string, handler func(*commonpb.Block) error) error {
+	manifest, err := ReadExportManifest(dir)
+	if err != nil {
+		return err
+	}
+	if manifest.Format != ExportFormatProtobuf {
+		return fmt.Errorf("Export in %s is not in %s format", dir, ExportFormatProtobuf)
+	}
+	for _, exportFile := range manifest.Files {
+		content, err := readExportFile(dir, exportFile)
+		if err != nil {
+			return err
+		}
+		for len(content) > 0 {
+			length, size := binary.Uvarint(content)
+			if size <= 0 || uint64(len(content)-size) < length {
+				return fmt.Errorf("Truncated block in %s", exportFile.Name)
+			}
+			block := new(commonpb.Block)
+			if err := proto.Unmarshal(content[size:size+int(length)], block); err != nil {
+				return fmt.Errorf("Invalid block in %s: %v", exportFile.Name, err)
+			}
+			if err := handler(block); err != nil {
+				return err
+			}
+			content = content[size+int(length):]
+		}
+	}
+	return nil
+}
+
+//ReadExportedDecodedBlocks calls handler with each decoded block of a protobuf or JSONL export in
+//the manifest order
+func ReadExportedDecodedBlocks(dir string, handler func(*DecodedBlock) error) error {
+	manifest, err := ReadExportManifest(dir)
+	if err != nil {
+		return err
+	}
+	if manifest.Format == ExportFormatProtobuf {
+		return ReadExportedBlocks(dir, func(block *commonpb.Block) error {
+			decoded, err := DecodeBlock(block)
+			if err != nil {
+				return err
+			}
+			return handler(decoded)
+		})
+	}
+	for _, exportFile := range manifest.Files {
+		content, err := readExportFile(dir, exportFile)
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 64*1024), len(content)+1)
+		for scanner.Scan() {
+			decoded := new(DecodedBlock)
+			if err := json.Unmarshal(scanner.Bytes(), decoded); err != nil {
+				return fmt.Errorf("Invalid block in %s: %v", exportFile.Name, err)
+			}
+			if err := handler(decoded); err != nil {
+				return err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("Unable to read %s: %v", exportFile.Name, err)
+		}
+	}
+	return nil
+}
+
+//readExportFile reads an export file and checks its hash against the manifest
+func readExportFile(dir string, exportFile *ExportManifestFile) ([]byte, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, exportFile.Name))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read export file: %v", err)
+	}
+	hash := sha256.Sum256(content)
+	if hex.EncodeToString(hash[:]) != exportFile.SHA256 {
+		return nil, fmt.Errorf("Hash of %s does not match the manifest", exportFile.Name)
+	}
+	return content, nil
+}
@@ -0,0 +1,359 @@
+package fabricgosdkclientcore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/options"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fab/events/deliverclient"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fab/events/deliverclient/seek"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	bolt "go.etcd.io/bbolt"
+)
+
+//Buckets of the block index. Index entries end with the height of the transaction, the block
+//number followed by the transaction index, so a prefix scan returns them in ledger order.
+var (
+	indexBucketMeta       = []byte("meta")
+	indexBucketTxs        = []byte("txs")
+	indexBucketTxIDs      = []byte("txids")
+	indexBucketKeys       = []byte("keys")
+	indexBucketEvents     = []byte("events")
+	indexBucketTimes      = []byte("times")
+	indexBucketCreators   = []byte("creators")
+	indexBucketChaincodes = []byte("chaincodes")
+	indexLastBlockKey     = []byte("lastBlock")
+)
+
+//IndexedTx is a transaction stored in the block index
+type IndexedTx struct {
+	TxID               string    `json:"txId"`
+	ChannelID          string    `json:"channelId"`
+	BlockNumber        uint64    `json:"blockNumber"`
+	TxIndex            int       `json:"txIndex"`
+	Type               string    `json:"type"`
+	Timestamp          time.Time `json:"timestamp"`
+	CreatorMSPID       string    `json:"creatorMspId"`
+	CreatorSubject     string    `json:"creatorSubject,omitempty"`
+	ChaincodeName      string    `json:"chaincodeName,omitempty"`
+	Function           string    `json:"function,omitempty"`
+	ValidationCode     int32     `json:"validationCode"`
+	ValidationCodeName string    `json:"validationCodeName"`
+	EventName          string    `json:"eventName,omitempty"`
+	WrittenKeys        []string  `json:"writtenKeys,omitempty"`
+}
+
+//IsValid returns true if the transaction was committed as valid
+func (itx *IndexedTx) IsValid() bool {
+	return itx.ValidationCode == int32(pb.TxValidationCode_VALID)
+}
+
+//indexedWrite is the value of a write set entry in the key index
+type indexedWrite struct {
+	Value    []byte `json:"value,omitempty"`
+	IsDelete bool   `json:"isDelete"`
+}
+
+//BlockIndexer stores decoded blocks in an embedded bbolt database and answers queries the peer
+//cannot, by key, chain code, chain code event, creator and time. The number of the last indexed block is
+//stored with each block, indexing resumes after it.
+type BlockIndexer struct {
+	db *bolt.DB
+}
+
+//OpenBlockIndexer opens or creates the index database at path
+func OpenBlockIndexer(path string) (*BlockIndexer, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open block index %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{indexBucketMeta, indexBucketTxs, indexBucketTxIDs, indexBucketKeys, indexBucketEvents, indexBucketTimes, indexBucketCreators, indexBucketChaincodes} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Unable to initialize block index %s: %v", path, err)
+	}
+	return &BlockIndexer{db: db}, nil
+}
+
+//Close closes the index database
+func (bi *BlockIndexer) Close() error {
+	return bi.db.Close()
+}
+
+//LastIndexedBlock returns the number of the last indexed block, false if no block is indexed
+func (bi *BlockIndexer) LastIndexedBlock() (uint64, bool) {
+	var lastBlock uint64
+	isFound := false
+	bi.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(indexBucketMeta).Get(indexLastBlockKey); value != nil {
+			lastBlock = binary.BigEndian.Uint64(value)
+			isFound = true
+		}
+		return nil
+	})
+	return lastBlock, isFound
+}
+
+//Index stores the transactions of a block. A block at or below the last indexed block is ignored.
+func (bi *BlockIndexer) Index(block *DecodedBlock) error {
+	if lastBlock, isFound := bi.LastIndexedBlock(); isFound && block.Number <= lastBlock {
+		return nil
+	}
+	return bi.db.Update(func(tx *bolt.Tx) error {
+		for _, transaction := range block.Transactions {
+			if err := indexTransaction(tx, block.Number, transaction); err != nil {
+				return fmt.Errorf("Unable to index transaction %s of block %d: %v", transaction.TxID, block.Number, err)
+			}
+		}
+		return tx.Bucket(indexBucketMeta).Put(indexLastBlockKey, uint64Bytes(block.Number))
+	})
+}
+
+func indexTransaction(tx *bolt.Tx, blockNumber uint64, transaction *DecodedTransaction) error {
+	height := txHeight(blockNumber, transaction.Index)
+	indexed := &IndexedTx{
+		TxID:               transaction.TxID,
+		ChannelID:          transaction.ChannelID,
+		BlockNumber:        blockNumber,
+		TxIndex:            transaction.Index,
+		Type:               transaction.Type,
+		Timestamp:          transaction.Timestamp,
+		CreatorMSPID:       transaction.CreatorMSPID,
+		CreatorSubject:     transaction.CreatorSubject,
+		ChaincodeName:      transaction.ChaincodeName,
+		Function:           transaction.Function,
+		ValidationCode:     transaction.ValidationCode,
+		ValidationCodeName: transaction.ValidationCodeName,
+	}
+	for _, rwSet := range transaction.RWSets {
+		for _, write := range rwSet.Writes {
+			indexed.WrittenKeys = append(indexed.WrittenKeys, rwSet.Namespace+"/"+write.Key)
+			writeBytes, err := json.Marshal(&indexedWrite{Value: write.Value, IsDelete: write.IsDelete})
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(indexBucketKeys).Put(indexKey(height, rwSet.Namespace, write.Key), writeBytes); err != nil {
+				return err
+			}
+		}
+	}
+	if transaction.ChaincodeEvent != nil {
+		indexed.EventName = transaction.ChaincodeEvent.EventName
+		if err := tx.Bucket(indexBucketEvents).Put(indexKey(height, transaction.ChaincodeEvent.ChaincodeID, transaction.ChaincodeEvent.EventName), nil); err != nil {
+			return err
+		}
+	}
+	txBytes, err := json.Marshal(indexed)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(indexBucketTxs).Put(height, txBytes); err != nil {
+		return err
+	}
+	if err := tx.Bucket(indexBucketTxIDs).Put(indexKey(height, transaction.TxID), nil); err != nil {
+		return err
+	}
+	if err := tx.Bucket(indexBucketCreators).Put(indexKey(height, transaction.CreatorMSPID), nil); err != nil {
+		return err
+	}
+	if len(transaction.ChaincodeName) > 0 {
+		if err := tx.Bucket(indexBucketChaincodes).Put(indexKey(height, transaction.ChaincodeName), nil); err != nil {
+			return err
+		}
+	}
+	timeKey := append(timeIndexKey(transaction.Timestamp), height...)
+	return tx.Bucket(indexBucketTimes).Put(timeKey, nil)
+}
+
+//TxsByID returns the transactions with the ID. Only one is valid, the others are rejected duplicates.
+func (bi *BlockIndexer) TxsByID(txID string) ([]*IndexedTx, error) {
+	return bi.scanPrefix(indexBucketTxIDs, indexPrefix(txID))
+}
+
+//TxsByKey returns the transactions which wrote or deleted the key of the chain code
+func (bi *BlockIndexer) TxsByKey(chaincode, key string) ([]*IndexedTx, error) {
+	return bi.scanPrefix(indexBucketKeys, indexPrefix(chaincode, key))
+}
+
+//TxsByChaincode returns the transactions invoking the chain code
+func (bi *BlockIndexer) TxsByChaincode(chaincode string) ([]*IndexedTx, error) {
+	return bi.scanPrefix(indexBucketChaincodes, indexPrefix(chaincode))
+}
+
+//TxsByEvent returns the transactions which emitted the chain code event
+func (bi *BlockIndexer) TxsByEvent(chaincode, eventName string) ([]*IndexedTx, error) {
+	return bi.scanPrefix(indexBucketEvents, indexPrefix(chaincode, eventName))
+}
+
+//TxsByCreator returns the transactions created by an identity of the MSP
+func (bi *BlockIndexer) TxsByCreator(mspID string) ([]*IndexedTx, error) {
+	return bi.scanPrefix(indexBucketCreators, indexPrefix(mspID))
+}
+
+//TxsInTimeRange returns the transactions with a timestamp from from, included, to to, excluded
+func (bi *BlockIndexer) TxsInTimeRange(from, to time.Time) ([]*IndexedTx, error) {
+	return bi.txsInTimeRange(from, to, false)
+}
+
+//InvalidTxsInTimeRange returns the invalid transactions with a timestamp from from, included, to to, excluded
+func (bi *BlockIndexer) InvalidTxsInTimeRange(from, to time.Time) ([]*IndexedTx, error) {
+	return bi.txsInTimeRange(from, to, true)
+}
+
+func (bi *BlockIndexer) txsInTimeRange(from, to time.Time, invalidOnly bool) ([]*IndexedTx, error) {
+	txs := make([]*IndexedTx, 0)
+	end := timeIndexKey(to)
+	err := bi.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(indexBucketTimes).Cursor()
+		for key, _ := cursor.Seek(timeIndexKey(from)); key != nil && bytes.Compare(key[:8], end) < 0; key, _ = cursor.Next() {
+			indexed, err := getIndexedTx(tx, key[8:])
+			if err != nil {
+				return err
+			}
+			if !invalidOnly || !indexed.IsValid() {
+				txs = append(txs, indexed)
+			}
+		}
+		return nil
+	})
+	return txs, err
+}
+
+//scanPrefix returns the transactions of the index entries starting with prefix
+func (bi *BlockIndexer) scanPrefix(bucket, prefix []byte) ([]*IndexedTx, error) {
+	txs := make([]*IndexedTx, 0)
+	err := bi.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucket).Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			if !isExactIndexKey(key, prefix) {
+				continue
+			}
+			indexed, err := getIndexedTx(tx, key[len(key)-txHeightLength:])
+			if err != nil {
+				return err
+			}
+			txs = append(txs, indexed)
+		}
+		return nil
+	})
+	return txs, err
+}
+
+func getIndexedTx(tx *bolt.Tx, height []byte) (*IndexedTx, error) {
+	txBytes := tx.Bucket(indexBucketTxs).Get(height)
+	if txBytes == nil {
+		return nil, fmt.Errorf("Index entry without transaction at block %d", binary.BigEndian.Uint64(height))
+	}
+	indexed := new(IndexedTx)
+	if err := json.Unmarshal(txBytes, indexed); err != nil {
+		return nil, fmt.Errorf("Invalid indexed transaction: %v", err)
+	}
+	return indexed, nil
+}
+
+//txHeightLength is the length of a height, the block number and the transaction index
+const txHeightLength = 12
+
+//txHeight returns the block number and transaction index as a sortable key
+func txHeight(blockNumber uint64, txIndex int) []byte {
+	height := make([]byte, txHeightLength)
+	binary.BigEndian.PutUint64(height, blockNumber)
+	binary.BigEndian.PutUint32(height[8:], uint32(txIndex))
+	return height
+}
+
+//indexPrefix joins the parts of an index key, each part followed by a zero byte
+func indexPrefix(parts ...string) []byte {
+	prefix := make([]byte, 0)
+	for _, part := range parts {
+		prefix = append(append(prefix, part...), 0)
+	}
+	return prefix
+}
+
+func indexKey(height []byte, parts ...string) []byte {
+	return append(indexPrefix(parts...), height...)
+}
+
+//isExactIndexKey checks the index key is the prefix followed by a height. A longer part starting
+//with the last part of the prefix and a zero byte, like a composite key, matches the prefix too.
+func isExactIndexKey(indexKey, prefix []byte) bool {
+	return len(indexKey) == len(prefix)+txHeightLength
+}
+
+//timeIndexKey is the sortable key of a time, the times before 1970 like the zero time are
+//clamped to 0 rather than overflowing
+func timeIndexKey(timestamp time.Time) []byte {
+	if timestamp.Before(time.Unix(0, 0)) {
+		return uint64Bytes(0)
+	}
+	return uint64Bytes(uint64(timestamp.UnixNano()))
+}
+
+func uint64Bytes(value uint64) []byte {
+	valueBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(valueBytes, value)
+	return valueBytes
+}
+
+//IndexExport indexes the blocks of a ledger export
+func (bi *BlockIndexer) IndexExport(dir string) error {
+	return ReadExportedDecodedBlocks(dir, bi.Index)
+}
+
+//RegisterBlockIndexer indexes the blocks of the channel as they are committed. The block events
+//start after the last indexed block, so the blocks committed while the indexer was not running
+//are indexed first. Indexing stops at the first failure, DegisterBlockIndexer stops it otherwise.
+func (fsc *FabricSDKClient) RegisterBlockIndexer(channelID string, indexer *BlockIndexer) bool {
+	evtOptions := []options.Opt{deliverclient.WithSeekType(seek.Oldest)}
+	if lastBlock, isFound := indexer.LastIndexedBlock(); isFound {
+		_logger.Infof("Resuming the block indexer of %s from block %d", channelID, lastBlock+1)
+		evtOptions = []options.Opt{deliverclient.WithSeekType(seek.FromBlock), deliverclient.WithBlockNum(lastBlock + 1)}
+	}
+	blockEventChan, isRegistered := fsc.registerAdminBlockEvents(channelID, fmt.Sprintf("%s_BLOCKINDEXER", channelID), evtOptions...)
+	if !isRegistered {
+		return false
+	}
+	//Shutdown waits for the blocks received to be indexed like for the event forwarders
+	fsc.forwarders.Add(1)
+	go fsc.indexBlockEvents(channelID, indexer, blockEventChan)
+	return true
+}
+
+func (fsc *FabricSDKClient) indexBlockEvents(channelID string, indexer *BlockIndexer, blockEventChan <-chan *fab.BlockEvent) {
+	defer fsc.forwarders.Done()
+	failed := false
+	for event := range blockEventChan {
+		//The channel is drained after a failure until the registration is removed
+		if failed {
+			continue
+		}
+		decodedBlock, err := DecodeBlock(event.Block)
+		if err == nil {
+			err = indexer.Index(decodedBlock)
+		}
+		if err != nil {
+			_logger.Errorf("Block indexer of %s stopped at block %d: %+v", channelID, event.Block.GetHeader().GetNumber(), err)
+			failed = true
+			go fsc.DegisterBlockIndexer(channelID)
+		}
+	}
+}
+
+//DegisterBlockIndexer stops the block indexer of the channel
+func (fsc *FabricSDKClient) DegisterBlockIndexer(channelID string) {
+	if evtWtGrp, isFound := fsc.removeEventFromRegistry(fmt.Sprintf("%s_BLOCKINDEXER", channelID)); isFound {
+		evtWtGrp.Deregister()
+	}
+}
@@ -0,0 +1,163 @@
+package fabricgosdkclientcore
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	ledger "github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	fabsdk "github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+)
+
+//DefaultBlockFetchConcurrency is the number of blocks fetched in parallel when no concurrency is given
+const DefaultBlockFetchConcurrency = 4
+
+//BlockFetcher fetches one block by its number
+type BlockFetcher func(blockNumber uint64) (*commonpb.Block, error)
+
+//BlockIterator iterates over a range of blocks in order. Blocks ahead of the current one are
+//fetched in parallel, up to the configured concurrency.
+type BlockIterator struct {
+	from        uint64
+	to          uint64
+	concurrency int
+	pending     chan chan blockFetchResult
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+type blockFetchResult struct {
+	blockNumber uint64
+	block       *commonpb.Block
+	err         error
+}
+
+//NewBlockIterator creates an iterator from block number from to block number to, both included.
+//The blocks are iterated backward when from is greater than to.
+func NewBlockIterator(fetcher BlockFetcher, from, to uint64, concurrency int) *BlockIterator {
+	if concurrency <= 0 {
+		concurrency = DefaultBlockFetchConcurrency
+	}
+	iterator := &BlockIterator{
+		from:        from,
+		to:          to,
+		concurrency: concurrency,
+		pending:     make(chan chan blockFetchResult, concurrency-1),
+		done:        make(chan struct{}),
+	}
+	go iterator.prefetch(fetcher)
+	return iterator
+}
+
+//NewLedgerBlockIterator creates an iterator over the blocks of the channel ledger
+func (fsc *FabricSDKClient) NewLedgerBlockIterator(channel string, from, to uint64, concurrency int) (*BlockIterator, error) {
+	fetcher, err := fsc.ledgerBlockFetcher(channel)
+	if err != nil {
+		return nil, err
+	}
+	return NewBlockIterator(fetcher, from, to, concurrency), nil
+}
+
+//ledgerBlockFetcher returns a fetcher of the blocks of the channel ledger. Each fetch is work in
+//flight, the ledger client is created again after EnrollTLS replaced the SDK.
+func (fsc *FabricSDKClient) ledgerBlockFetcher(channel string) (BlockFetcher, error) {
+	var lock sync.Mutex
+	var sdk *fabsdk.FabricSDK
+	var ledgerClient *ledger.Client
+	currentLedgerClient := func() (*ledger.Client, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if currentSDK := fsc.getSDK(); ledgerClient == nil || currentSDK != sdk {
+			client, err := fsc.getLedgerClient(channel)
+			if err != nil {
+				return nil, err
+			}
+			sdk, ledgerClient = currentSDK, client
+		}
+		return ledgerClient, nil
+	}
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	_, err := currentLedgerClient()
+	fsc.endWork()
+	if err != nil {
+		return nil, err
+	}
+	return func(blockNumber uint64) (*commonpb.Block, error) {
+		if err := fsc.beginWork(); err != nil {
+			return nil, err
+		}
+		defer fsc.endWork()
+		client, err := currentLedgerClient()
+		if err != nil {
+			return nil, err
+		}
+		return client.QueryBlock(blockNumber)
+	}, nil
+}
+
+//IsForward returns true if the block numbers increase during the iteration
+func (bi *BlockIterator) IsForward() bool {
+	return bi.from <= bi.to
+}
+
+//Len returns the number of blocks in the range
+func (bi *BlockIterator) Len() uint64 {
+	if bi.IsForward() {
+		return bi.to - bi.from + 1
+	}
+	return bi.from - bi.to + 1
+}
+
+//prefetch starts the fetch of each block of the range. The block waited for by Next and the
+//buffered pending results keep at most concurrency fetches running.
+func (bi *BlockIterator) prefetch(fetcher BlockFetcher) {
+	defer close(bi.pending)
+	blockNumber := bi.from
+	for count := uint64(0); count < bi.Len(); count++ {
+		resultChan := make(chan blockFetchResult, 1)
+		select {
+		case bi.pending <- resultChan:
+		case <-bi.done:
+			return
+		}
+		go func(blockNumber uint64) {
+			block, err := fetcher(blockNumber)
+			if err != nil {
+				err = fmt.Errorf("Error in retriving the block %d: %v", blockNumber, err)
+			}
+			resultChan <- blockFetchResult{blockNumber: blockNumber, block: block, err: err}
+		}(blockNumber)
+		if bi.IsForward() {
+			blockNumber++
+		} else {
+			blockNumber--
+		}
+	}
+}
+
+//Next returns the next block of the range. io.EOF is returned after the last block.
+func (bi *BlockIterator) Next() (*commonpb.Block, error) {
+	select {
+	case resultChan, ok := <-bi.pending:
+		if !ok {
+			return nil, io.EOF
+		}
+		result := <-resultChan
+		if result.err != nil {
+			return nil, result.err
+		}
+		return result.block, nil
+	case <-bi.done:
+		return nil, io.EOF
+	}
+}
+
+//Close stops fetching further blocks
+func (bi *BlockIterator) Close() {
+	bi.closeOnce.Do(func() {
+		close(bi.done)
+	})
+}
@@ -0,0 +1,239 @@
+package fabricgosdkclientcore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//DefaultCertExpiryCheckInterval is the interval between two scans of the certificates
+const DefaultCertExpiryCheckInterval = time.Hour
+
+//DefaultCertExpiryThresholds are the times to expiry warned about when no threshold is configured
+var DefaultCertExpiryThresholds = []time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour}
+
+//CertExpiryConfig configures the certificate expiry monitor. The certificates closer to expiry
+//than ReenrollBefore are renewed, 0 only warns.
+type CertExpiryConfig struct {
+	CheckInterval     time.Duration
+	WarningThresholds []time.Duration
+	ReenrollBefore    time.Duration
+	//Users are monitored in addition to the org admin, the preloaded users and the enrolled users
+	Users []string
+}
+
+//CertExpiry is the expiry state of the enrollment certificate of an identity. Threshold is the
+//smallest warning threshold crossed, 0 if none.
+type CertExpiry struct {
+	UserID       string        `json:"userId"`
+	Serial       string        `json:"serial"`
+	NotAfter     time.Time     `json:"notAfter"`
+	TimeToExpiry time.Duration `json:"timeToExpiry"`
+	Expired      bool          `json:"expired"`
+	Threshold    time.Duration `json:"threshold,omitempty"`
+	Reenrolled   bool          `json:"reenrolled"`
+	Error        string        `json:"error,omitempty"`
+}
+
+//CertExpiryListener is called by the monitor when a certificate crosses a warning threshold, is
+//renewed or can not be checked
+type CertExpiryListener func(*CertExpiry)
+
+func (fsc *FabricSDKClient) addEnrolledUser(uid string) {
+	fsc.clientsLock.Lock()
+	defer fsc.clientsLock.Unlock()
+	fsc.enrolledUsers[uid] = true
+}
+
+//monitoredUsers returns the org admin, the users enrolled through the client, the users of the
+//channel clients, the users of the wallet and the extra users, sorted
+func (fsc *FabricSDKClient) monitoredUsers(extraUsers []string) []string {
+	userSet := make(map[string]bool)
+	if fsc.isRemoteAdmin {
+		userSet[fsc.remoteAdminID] = true
+	} else if len(fsc.orgAdmin) > 0 {
+		userSet[fsc.orgAdmin] = true
+	}
+	fsc.clientsLock.RLock()
+	for uid := range fsc.enrolledUsers {
+		userSet[uid] = true
+	}
+	for key := range fsc.channelClientMap {
+		//Channel names can not contain an underscore, the user is after the first one
+		userSet[key[strings.Index(key, "_")+1:]] = true
+	}
+	fsc.clientsLock.RUnlock()
+	if wallet := fsc.Wallet(); wallet != nil {
+		if walletUsers, err := wallet.List(); err == nil {
+			extraUsers = append(walletUsers, extraUsers...)
+		}
+	}
+	for _, uid := range extraUsers {
+		userSet[uid] = true
+	}
+	users := make([]string, 0, len(userSet))
+	for uid := range userSet {
+		users = append(users, uid)
+	}
+	sort.Strings(users)
+	return users
+}
+
+func (fsc *FabricSDKClient) certExpiryOf(uid string, thresholds []time.Duration) (*CertExpiry, error) {
+	certPEM, err := fsc.userCertificate(uid)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := parsePEMCertificate(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("Enrollment certificate of %s: %v", uid, err)
+	}
+	expiry := &CertExpiry{
+		UserID:       uid,
+		Serial:       certificate.SerialNumber.Text(16),
+		NotAfter:     certificate.NotAfter,
+		TimeToExpiry: time.Until(certificate.NotAfter),
+	}
+	expiry.Expired = expiry.TimeToExpiry <= 0
+	for _, threshold := range thresholds {
+		if expiry.TimeToExpiry <= threshold && (expiry.Threshold == 0 || threshold < expiry.Threshold) {
+			expiry.Threshold = threshold
+		}
+	}
+	return expiry, nil
+}
+
+//CheckCertExpiry reports the expiry of the enrollment certificates of the identities used by the
+//client and renews the ones closer to expiry than config.ReenrollBefore
+func (fsc *FabricSDKClient) CheckCertExpiry(config CertExpiryConfig) []*CertExpiry {
+	thresholds := config.WarningThresholds
+	if len(thresholds) == 0 {
+		thresholds = DefaultCertExpiryThresholds
+	}
+	expiries := make([]*CertExpiry, 0)
+	for _, uid := range fsc.monitoredUsers(config.Users) {
+		expiry, err := fsc.certExpiryOf(uid, thresholds)
+		if err != nil {
+			expiries = append(expiries, &CertExpiry{UserID: uid, Error: err.Error()})
+			continue
+		}
+		if config.ReenrollBefore > 0 && expiry.TimeToExpiry < config.ReenrollBefore {
+			if err := fsc.renewEnrollment(uid); err != nil {
+				expiry.Error = err.Error()
+			} else if renewed, err := fsc.certExpiryOf(uid, thresholds); err != nil {
+				expiry.Error = err.Error()
+			} else {
+				renewed.Reenrolled = true
+				expiry = renewed
+			}
+		}
+		expiries = append(expiries, expiry)
+	}
+	return expiries
+}
+
+//renewEnrollment reenrolls the user and recreates its channel clients with the new certificate.
+//An expired certificate can not be used to reenroll, the registrar enrolls again with its secret.
+func (fsc *FabricSDKClient) renewEnrollment(uid string) error {
+	if fsc.isRemoteAdmin && uid == fsc.remoteAdminID {
+		return fmt.Errorf("Certificate of the remote admin %s is pre generated and can not be renewed", uid)
+	}
+	err := fsc.ReenrollUser(uid, nil)
+	if err != nil && uid == fsc.orgAdmin && len(fsc.orgAdminSecret) > 0 {
+		err = fsc.EnrollUser(uid, fsc.orgAdminSecret, nil)
+	}
+	if err != nil {
+		return err
+	}
+	_logger.Infof("Enrollment certificate of %s renewed", uid)
+	fsc.refreshChannelClients(uid)
+	return nil
+}
+
+//refreshChannelClients recreates the cached channel clients of the user
+func (fsc *FabricSDKClient) refreshChannelClients(uid string) {
+	channelNames := make([]string, 0)
+	fsc.clientsLock.RLock()
+	for key := range fsc.channelClientMap {
+		if separator := strings.Index(key, "_"); key[separator+1:] == uid {
+			channelNames = append(channelNames, key[:separator])
+		}
+	}
+	fsc.clientsLock.RUnlock()
+	for _, channelName := range channelNames {
+		if _, isSetup := fsc.setupChannelClient(channelName, uid); !isSetup {
+			_logger.Errorf("Unable to refresh the channel client of %s in %s", uid, channelName)
+		}
+	}
+}
+
+//StartCertExpiryMonitor checks the certificates periodically until StopCertExpiryMonitor or
+//Shutdown. Each threshold crossed by a certificate is logged and given to the listener once,
+//listener may be nil.
+func (fsc *FabricSDKClient) StartCertExpiryMonitor(config CertExpiryConfig, listener CertExpiryListener) bool {
+	fsc.lifecycleLock.Lock()
+	defer fsc.lifecycleLock.Unlock()
+	if fsc.isShutdown {
+		_logger.Errorf("Client is shut down, certificate expiry monitor not started")
+		return false
+	}
+	if fsc.certMonitor != nil {
+		_logger.Errorf("Certificate expiry monitor is running already")
+		return false
+	}
+	interval := config.CheckInterval
+	if interval <= 0 {
+		interval = DefaultCertExpiryCheckInterval
+	}
+	stop := make(chan struct{})
+	fsc.certMonitor = stop
+	fsc.forwarders.Add(1)
+	go func() {
+		defer fsc.forwarders.Done()
+		warned := make(map[string]time.Duration)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			//The check is work in flight, EnrollTLS does not replace the SDK in the middle of it
+			if fsc.beginWork() != nil {
+				return
+			}
+			expiries := fsc.CheckCertExpiry(config)
+			fsc.endWork()
+			for _, expiry := range expiries {
+				isNew := expiry.Threshold > 0 && (warned[expiry.Serial] == 0 || expiry.Threshold < warned[expiry.Serial])
+				switch {
+				case len(expiry.Error) > 0:
+					_logger.Errorf("Certificate expiry check of %s failed: %s", expiry.UserID, expiry.Error)
+				case expiry.Reenrolled:
+					_logger.Infof("Certificate of %s renewed, valid until %v", expiry.UserID, expiry.NotAfter)
+				case isNew:
+					_logger.Warningf("Certificate %s of %s expires in %v at %v", expiry.Serial, expiry.UserID, expiry.TimeToExpiry, expiry.NotAfter)
+					warned[expiry.Serial] = expiry.Threshold
+				default:
+					continue
+				}
+				if listener != nil {
+					listener(expiry)
+				}
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return true
+}
+
+//StopCertExpiryMonitor stops the certificate expiry monitor
+func (fsc *FabricSDKClient) StopCertExpiryMonitor() {
+	fsc.lifecycleLock.Lock()
+	defer fsc.lifecycleLock.Unlock()
+	if fsc.certMonitor != nil {
+		close(fsc.certMonitor)
+		fsc.certMonitor = nil
+	}
+}
@@ -0,0 +1,123 @@
+package fabricgosdkclientcore
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//Roles of an identity given by the node organizational units of its certificate
+const (
+	CertRoleClient  = "client"
+	CertRolePeer    = "peer"
+	CertRoleAdmin   = "admin"
+	CertRoleOrderer = "orderer"
+	CertRoleMember  = "member"
+)
+
+//fabricCAAttrsOID is the certificate extension holding the attributes added by the Fabric CA
+var fabricCAAttrsOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+//CertificateInfo is the content of an X.509 certificate of a Fabric identity. Role is taken from
+//the node organizational units and Attributes from the Fabric CA attribute extension.
+type CertificateInfo struct {
+	Subject             string            `json:"subject"`
+	SubjectCommonName   string            `json:"subjectCommonName"`
+	Issuer              string            `json:"issuer"`
+	IssuerCommonName    string            `json:"issuerCommonName"`
+	OrganizationalUnits []string          `json:"organizationalUnits,omitempty"`
+	Serial              string            `json:"serial"`
+	NotBefore           time.Time         `json:"notBefore"`
+	NotAfter            time.Time         `json:"notAfter"`
+	Expired             bool              `json:"expired"`
+	KeyAlgorithm        string            `json:"keyAlgorithm"`
+	SignatureAlgorithm  string            `json:"signatureAlgorithm"`
+	IsCA                bool              `json:"isCA"`
+	DNSNames            []string          `json:"dnsNames,omitempty"`
+	IPAddresses         []string          `json:"ipAddresses,omitempty"`
+	EmailAddresses      []string          `json:"emailAddresses,omitempty"`
+	SubjectKeyID        string            `json:"subjectKeyId,omitempty"`
+	AuthorityKeyID      string            `json:"authorityKeyId,omitempty"`
+	Role                string            `json:"role"`
+	Attributes          map[string]string `json:"attributes,omitempty"`
+}
+
+//InspectCertificate decodes the first certificate of PEM bytes
+func InspectCertificate(pemBytes []byte) (*CertificateInfo, error) {
+	certificate, err := parsePEMCertificate(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	return NewCertificateInfo(certificate)
+}
+
+//NewCertificateInfo decodes a parsed certificate
+func NewCertificateInfo(certificate *x509.Certificate) (*CertificateInfo, error) {
+	info := &CertificateInfo{
+		Subject:             certificate.Subject.String(),
+		SubjectCommonName:   certificate.Subject.CommonName,
+		Issuer:              certificate.Issuer.String(),
+		IssuerCommonName:    certificate.Issuer.CommonName,
+		OrganizationalUnits: certificate.Subject.OrganizationalUnit,
+		Serial:              certificate.SerialNumber.Text(16),
+		NotBefore:           certificate.NotBefore,
+		NotAfter:            certificate.NotAfter,
+		Expired:             time.Now().After(certificate.NotAfter),
+		KeyAlgorithm:        keyAlgorithm(certificate),
+		SignatureAlgorithm:  certificate.SignatureAlgorithm.String(),
+		IsCA:                certificate.IsCA,
+		DNSNames:            certificate.DNSNames,
+		EmailAddresses:      certificate.EmailAddresses,
+		SubjectKeyID:        hex.EncodeToString(certificate.SubjectKeyId),
+		AuthorityKeyID:      hex.EncodeToString(certificate.AuthorityKeyId),
+		Role:                CertRoleMember,
+	}
+	for _, ipAddress := range certificate.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ipAddress.String())
+	}
+	for _, organizationalUnit := range certificate.Subject.OrganizationalUnit {
+		switch role := strings.ToLower(organizationalUnit); role {
+		case CertRoleClient, CertRolePeer, CertRoleAdmin, CertRoleOrderer:
+			info.Role = role
+		}
+	}
+	for _, extension := range certificate.Extensions {
+		if !extension.Id.Equal(fabricCAAttrsOID) {
+			continue
+		}
+		attrs := struct {
+			Attrs map[string]string `json:"attrs"`
+		}{}
+		if err := json.Unmarshal(extension.Value, &attrs); err != nil {
+			return nil, fmt.Errorf("Invalid Fabric CA attributes of %s: %v", info.Subject, err)
+		}
+		info.Attributes = attrs.Attrs
+	}
+	return info, nil
+}
+
+func keyAlgorithm(certificate *x509.Certificate) string {
+	switch publicKey := certificate.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", publicKey.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", publicKey.N.BitLen())
+	}
+	return certificate.PublicKeyAlgorithm.String()
+}
+
+//InspectUserCertificate decodes the enrollment certificate of a user of the wallet or of the
+//credential store
+func (fsc *FabricSDKClient) InspectUserCertificate(uid string) (*CertificateInfo, error) {
+	certPEM, err := fsc.userCertificate(uid)
+	if err != nil {
+		return nil, err
+	}
+	return InspectCertificate(certPEM)
+}
@@ -0,0 +1,309 @@
+package fabricgosdkclientcore
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	ordererpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/orderer"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+)
+
+//Config values with a readable diff
+const (
+	configValueBatchSize   = "BatchSize"
+	configValueAnchorPeers = "AnchorPeers"
+)
+
+//ChannelConfigVersion is one config block of a channel with the changes from the config before it
+type ChannelConfigVersion struct {
+	BlockNumber uint64         `json:"blockNumber"`
+	Sequence    uint64         `json:"sequence"`
+	Timestamp   time.Time      `json:"timestamp"`
+	Config      *DecodedConfig `json:"config"`
+	Changes     []string       `json:"changes,omitempty"`
+}
+
+//ConfigUpdateListener is called with each config block committed in a channel
+type ConfigUpdateListener func(*ChannelConfigVersion)
+
+//NewChannelConfigVersion decodes a config block and lists the changes from the previous config,
+//which is nil for the genesis block
+func NewChannelConfigVersion(configBlock *commonpb.Block, previous *commonpb.Config) (*ChannelConfigVersion, error) {
+	config, err := blockChannelConfig(configBlock)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, fmt.Errorf("Block %d is not a config block", configBlock.GetHeader().GetNumber())
+	}
+	decodedBlock, err := DecodeBlock(configBlock)
+	if err != nil {
+		return nil, err
+	}
+	transaction := decodedBlock.Transactions[0]
+	return &ChannelConfigVersion{
+		BlockNumber: decodedBlock.Number,
+		Sequence:    config.Sequence,
+		Timestamp:   transaction.Timestamp,
+		Config:      transaction.Config,
+		Changes:     DiffChannelConfigs(previous, config),
+	}, nil
+}
+
+//GetChannelConfigHistory returns the config blocks of the channel, oldest first. The config blocks
+//are found by following the last config pointers of the block metadata from the newest block.
+func (fsc *FabricSDKClient) GetChannelConfigHistory(channel string) ([]*ChannelConfigVersion, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	ledgerClient, err := fsc.getLedgerClient(channel)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ledgerClient.QueryInfo()
+	if err != nil {
+		return nil, fmt.Errorf("Error in querying ledger info of %s: %v", channel, err)
+	}
+	fetcher := func(blockNumber uint64) (*commonpb.Block, error) {
+		return ledgerClient.QueryBlock(blockNumber)
+	}
+	return ChannelConfigHistory(fetcher, info.BCI.GetHeight()-1)
+}
+
+//ChannelConfigHistory returns the config blocks up to the block number lastBlock, oldest first
+func ChannelConfigHistory(fetcher BlockFetcher, lastBlock uint64) ([]*ChannelConfigVersion, error) {
+	configBlocks := make([]*commonpb.Block, 0)
+	blockNumber := lastBlock
+	for {
+		block, err := fetcher(blockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("Error in retriving the block %d: %v", blockNumber, err)
+		}
+		configIndex, err := LastConfigIndex(block)
+		if err != nil {
+			return nil, err
+		}
+		configBlock := block
+		if configIndex != blockNumber {
+			if configBlock, err = fetcher(configIndex); err != nil {
+				return nil, fmt.Errorf("Error in retriving the config block %d: %v", configIndex, err)
+			}
+		}
+		configBlocks = append(configBlocks, configBlock)
+		if configIndex == 0 {
+			break
+		}
+		blockNumber = configIndex - 1
+	}
+	history := make([]*ChannelConfigVersion, 0, len(configBlocks))
+	var previous *commonpb.Config
+	for index := len(configBlocks) - 1; index >= 0; index-- {
+		version, err := NewChannelConfigVersion(configBlocks[index], previous)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, version)
+		previous, _ = blockChannelConfig(configBlocks[index])
+	}
+	return history, nil
+}
+
+//DiffChannelConfigs describes the changes between two channel configs: organizations added or
+//removed, anchor peers, batch size, policies and other values
+func DiffChannelConfigs(previous, current *commonpb.Config) []string {
+	changes := make([]string, 0)
+	if previous == nil {
+		return changes
+	}
+	diffConfigGroups(&changes, "Channel", previous.ChannelGroup, current.ChannelGroup)
+	return changes
+}
+
+func diffConfigGroups(changes *[]string, path string, previous, current *commonpb.ConfigGroup) {
+	isOrgParent := path == "Channel/"+ConfigGroupApplication || path == "Channel/"+ConfigGroupOrderer
+	for _, name := range unionKeys(previous.GetGroups(), current.GetGroups()) {
+		previousGroup, currentGroup := previous.GetGroups()[name], current.GetGroups()[name]
+		groupPath := path + "/" + name
+		switch {
+		case previousGroup == nil && isOrgParent:
+			*changes = append(*changes, fmt.Sprintf("%s organization %s (%s) added", strings.TrimPrefix(path, "Channel/"), name, orgMSPID(currentGroup)))
+		case currentGroup == nil && isOrgParent:
+			*changes = append(*changes, fmt.Sprintf("%s organization %s (%s) removed", strings.TrimPrefix(path, "Channel/"), name, orgMSPID(previousGroup)))
+		case previousGroup == nil:
+			*changes = append(*changes, fmt.Sprintf("Group %s added", groupPath))
+		case currentGroup == nil:
+			*changes = append(*changes, fmt.Sprintf("Group %s removed", groupPath))
+		default:
+			diffConfigGroups(changes, groupPath, previousGroup, currentGroup)
+		}
+	}
+	for _, name := range unionKeys(previous.GetValues(), current.GetValues()) {
+		previousValue, currentValue := previous.GetValues()[name], current.GetValues()[name]
+		valuePath := path + "/" + name
+		switch {
+		case previousValue == nil:
+			*changes = append(*changes, fmt.Sprintf("Value %s added", valuePath))
+		case currentValue == nil:
+			*changes = append(*changes, fmt.Sprintf("Value %s removed", valuePath))
+		case bytes.Equal(previousValue.Value, currentValue.Value):
+		case name == configValueBatchSize:
+			*changes = append(*changes, fmt.Sprintf("Batch size changed from %s to %s", describeBatchSize(previousValue.Value), describeBatchSize(currentValue.Value)))
+		case name == configValueAnchorPeers:
+			*changes = append(*changes, fmt.Sprintf("Anchor peers of %s changed from %s to %s", path[strings.LastIndex(path, "/")+1:], describeAnchorPeers(previousValue.Value), describeAnchorPeers(currentValue.Value)))
+		default:
+			*changes = append(*changes, fmt.Sprintf("Value %s changed", valuePath))
+		}
+	}
+	for _, name := range unionKeys(previous.GetPolicies(), current.GetPolicies()) {
+		previousPolicy, currentPolicy := previous.GetPolicies()[name], current.GetPolicies()[name]
+		policyPath := path + "/" + name
+		switch {
+		case previousPolicy == nil:
+			*changes = append(*changes, fmt.Sprintf("Policy %s added", policyPath))
+		case currentPolicy == nil:
+			*changes = append(*changes, fmt.Sprintf("Policy %s removed", policyPath))
+		case !proto.Equal(previousPolicy.GetPolicy(), currentPolicy.GetPolicy()) || previousPolicy.ModPolicy != currentPolicy.ModPolicy:
+			*changes = append(*changes, fmt.Sprintf("Policy %s changed", policyPath))
+		}
+	}
+}
+
+func describeBatchSize(value []byte) string {
+	batchSize := new(ordererpb.BatchSize)
+	if err := proto.Unmarshal(value, batchSize); err != nil {
+		return "invalid batch size"
+	}
+	return fmt.Sprintf("[max message count %d, absolute max bytes %d, preferred max bytes %d]", batchSize.MaxMessageCount, batchSize.AbsoluteMaxBytes, batchSize.PreferredMaxBytes)
+}
+
+func describeAnchorPeers(value []byte) string {
+	anchorPeers := new(pb.AnchorPeers)
+	if err := proto.Unmarshal(value, anchorPeers); err != nil {
+		return "invalid anchor peers"
+	}
+	endpoints := make([]string, 0, len(anchorPeers.AnchorPeers))
+	for _, anchorPeer := range anchorPeers.AnchorPeers {
+		endpoints = append(endpoints, fmt.Sprintf("%s:%d", anchorPeer.Host, anchorPeer.Port))
+	}
+	return "[" + strings.Join(endpoints, " ") + "]"
+}
+
+//unionKeys returns the sorted keys of two config maps of the same type
+func unionKeys(previous, current interface{}) []string {
+	keySet := make(map[string]bool)
+	switch previousMap := previous.(type) {
+	case map[string]*commonpb.ConfigGroup:
+		for key := range previousMap {
+			keySet[key] = true
+		}
+		for key := range current.(map[string]*commonpb.ConfigGroup) {
+			keySet[key] = true
+		}
+	case map[string]*commonpb.ConfigValue:
+		for key := range previousMap {
+			keySet[key] = true
+		}
+		for key := range current.(map[string]*commonpb.ConfigValue) {
+			keySet[key] = true
+		}
+	case map[string]*commonpb.ConfigPolicy:
+		for key := range previousMap {
+			keySet[key] = true
+		}
+		for key := range current.(map[string]*commonpb.ConfigPolicy) {
+			keySet[key] = true
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//channelConfigInForce returns the config in force at the newest block of the channel
+func (fsc *FabricSDKClient) channelConfigInForce(channelID string) (*commonpb.Config, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	ledgerClient, err := fsc.getLedgerClient(channelID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ledgerClient.QueryInfo()
+	if err != nil {
+		return nil, fmt.Errorf("Error in querying ledger info of %s: %v", channelID, err)
+	}
+	lastBlock, err := ledgerClient.QueryBlock(info.BCI.GetHeight() - 1)
+	if err != nil {
+		return nil, fmt.Errorf("Error in retriving the block %v", err)
+	}
+	configIndex, err := LastConfigIndex(lastBlock)
+	if err != nil {
+		return nil, err
+	}
+	configBlock, err := ledgerClient.QueryBlock(configIndex)
+	if err != nil {
+		return nil, fmt.Errorf("Error in retriving the config block %v", err)
+	}
+	return blockChannelConfig(configBlock)
+}
+
+//RegisterConfigUpdateListener calls the listener with each config block committed in the channel
+//after the registration, with the changes from the config in force at the registration.
+//DegisterConfigUpdateListener stops it.
+func (fsc *FabricSDKClient) RegisterConfigUpdateListener(channelID string, listener ConfigUpdateListener) bool {
+	current, err := fsc.channelConfigInForce(channelID)
+	if err != nil {
+		_logger.Errorf("%+v", err)
+		return false
+	}
+	if current == nil {
+		_logger.Errorf("No channel config found for %s", channelID)
+		return false
+	}
+	blockEventChan, isRegistered := fsc.registerAdminBlockEvents(channelID, fmt.Sprintf("%s_CONFIGUPDATE", channelID))
+	if !isRegistered {
+		return false
+	}
+	//Shutdown waits for the config blocks received to be passed to the listener
+	fsc.forwarders.Add(1)
+	go func(blockEventChan <-chan *fab.BlockEvent, current *commonpb.Config) {
+		defer fsc.forwarders.Done()
+		for event := range blockEventChan {
+			config, err := blockChannelConfig(event.Block)
+			if err != nil {
+				_logger.Errorf("Unable to decode block %d of channel %s: %+v", event.Block.GetHeader().GetNumber(), channelID, err)
+				continue
+			}
+			//The subscription starts at the newest block, which may be the config in force already
+			if config == nil || config.Sequence <= current.Sequence {
+				continue
+			}
+			version, err := NewChannelConfigVersion(event.Block, current)
+			if err != nil {
+				_logger.Errorf("Unable to decode config block %d of channel %s: %+v", event.Block.GetHeader().GetNumber(), channelID, err)
+				continue
+			}
+			current = config
+			listener(version)
+		}
+	}(blockEventChan, current)
+	return true
+}
+
+//DegisterConfigUpdateListener stops the config update listener of the channel
+func (fsc *FabricSDKClient) DegisterConfigUpdateListener(channelID string) {
+	if evtWtGrp, isFound := fsc.removeEventFromRegistry(fmt.Sprintf("%s_CONFIGUPDATE", channelID)); isFound {
+		evtWtGrp.Deregister()
+	}
+}
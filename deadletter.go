@@ -0,0 +1,277 @@
+package fabricgosdkclientcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultDeadLetterPath is used when event sinks are configured without x-deadLetter
+	DefaultDeadLetterPath       = "./tmp/deadletters"
+	defaultDeadLetterMaxEntries = 1000
+)
+
+//DeadLetter is an event which could not be delivered to a sink or handler
+type DeadLetter struct {
+	ID            string     `json:"id"`
+	Subscription  string     `json:"subscription"`
+	Sink          string     `json:"sink"`
+	Event         *SinkEvent `json:"event"`
+	Error         string     `json:"error"`
+	Attempts      int        `json:"attempts"`
+	FirstFailedAt time.Time  `json:"firstFailedAt"`
+	LastFailedAt  time.Time  `json:"lastFailedAt"`
+}
+
+//DeadLetterConfig is the x-deadLetter entry of the client configuration
+type DeadLetterConfig struct {
+	Path       string `mapstructure:"path"`
+	MaxEntries int    `mapstructure:"maxEntries"`
+}
+
+//DeadLetterStore keeps the dead letters as one JSON file each in a directory. The store is
+//bounded, when it is full the oldest dead letter is dropped to make room for a new one.
+type DeadLetterStore struct {
+	dir        string
+	maxEntries int
+	lock       sync.Mutex
+	//sequence keeps the IDs of the letters added within the same clock tick unique and ordered
+	sequence uint64
+}
+
+//NewDeadLetterStore creates a dead letter store in dir holding at most maxEntries dead letters
+func NewDeadLetterStore(dir string, maxEntries int) (*DeadLetterStore, error) {
+	if maxEntries <= 0 {
+		maxEntries = defaultDeadLetterMaxEntries
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Unable to create dead letter directory %s: %v", dir, err)
+	}
+	return &DeadLetterStore{dir: dir, maxEntries: maxEntries}, nil
+}
+
+//Add saves a new dead letter. The ID is assigned by the store.
+func (dls *DeadLetterStore) Add(letter *DeadLetter) error {
+	dls.lock.Lock()
+	defer dls.lock.Unlock()
+	ids, err := dls.ids()
+	if err != nil {
+		return err
+	}
+	for len(ids) >= dls.maxEntries {
+		_logger.Warningf("Dead letter store %s is full, dropping %s", dls.dir, ids[0])
+		if err := os.Remove(dls.pathOf(ids[0])); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	dls.sequence++
+	letter.ID = fmt.Sprintf("%020d-%010d-%s", time.Now().UnixNano(), dls.sequence, sanitizeFileName(letter.Sink))
+	return dls.save(letter)
+}
+
+//List returns all dead letters, oldest first
+func (dls *DeadLetterStore) List() ([]*DeadLetter, error) {
+	dls.lock.Lock()
+	defer dls.lock.Unlock()
+	ids, err := dls.ids()
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]*DeadLetter, 0, len(ids))
+	for _, id := range ids {
+		letter, err := dls.load(id)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+//Get returns one dead letter
+func (dls *DeadLetterStore) Get(id string) (*DeadLetter, error) {
+	dls.lock.Lock()
+	defer dls.lock.Unlock()
+	return dls.load(id)
+}
+
+//Update saves the changes of an existing dead letter
+func (dls *DeadLetterStore) Update(letter *DeadLetter) error {
+	dls.lock.Lock()
+	defer dls.lock.Unlock()
+	if _, err := os.Stat(dls.pathOf(letter.ID)); err != nil {
+		return fmt.Errorf("Dead letter %s not found", letter.ID)
+	}
+	return dls.save(letter)
+}
+
+//Remove deletes a dead letter
+func (dls *DeadLetterStore) Remove(id string) error {
+	dls.lock.Lock()
+	defer dls.lock.Unlock()
+	if err := os.Remove(dls.pathOf(id)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("Dead letter %s not found", id)
+		}
+		return err
+	}
+	return nil
+}
+
+//Purge deletes the given dead letters or all of them if no ID is given.
+//It returns the number of dead letters deleted.
+func (dls *DeadLetterStore) Purge(ids ...string) (int, error) {
+	dls.lock.Lock()
+	defer dls.lock.Unlock()
+	if len(ids) == 0 {
+		existingIds, err := dls.ids()
+		if err != nil {
+			return 0, err
+		}
+		ids = existingIds
+	}
+	purged := 0
+	for _, id := range ids {
+		if err := os.Remove(dls.pathOf(id)); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (dls *DeadLetterStore) ids() ([]string, error) {
+	files, err := ioutil.ReadDir(dls.dir)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(file.Name(), ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (dls *DeadLetterStore) pathOf(id string) string {
+	return filepath.Join(dls.dir, sanitizeFileName(id)+".json")
+}
+
+func (dls *DeadLetterStore) load(id string) (*DeadLetter, error) {
+	content, err := ioutil.ReadFile(dls.pathOf(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("Dead letter %s not found", id)
+		}
+		return nil, err
+	}
+	letter := new(DeadLetter)
+	if err := json.Unmarshal(content, letter); err != nil {
+		return nil, fmt.Errorf("Invalid dead letter %s: %v", id, err)
+	}
+	return letter, nil
+}
+
+func (dls *DeadLetterStore) save(letter *DeadLetter) error {
+	content, err := json.MarshalIndent(letter, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dls.pathOf(letter.ID), content)
+}
+
+//sanitizeFileName keeps only characters safe for a file name
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+//SetDeadLetterStore replaces the dead letter store of the event sinks, for example to keep the
+//dead letters of sinks added with AddEventSink out of DefaultDeadLetterPath
+func (fsc *FabricSDKClient) SetDeadLetterStore(store *DeadLetterStore) {
+	fsc.deadLetters = store
+}
+
+//ListDeadLetters returns the dead lettered events, oldest first
+func (fsc *FabricSDKClient) ListDeadLetters() ([]*DeadLetter, error) {
+	if fsc.deadLetters == nil {
+		return []*DeadLetter{}, nil
+	}
+	return fsc.deadLetters.List()
+}
+
+//GetDeadLetter returns one dead lettered event
+func (fsc *FabricSDKClient) GetDeadLetter(id string) (*DeadLetter, error) {
+	if fsc.deadLetters == nil {
+		return nil, fmt.Errorf("Dead letter %s not found", id)
+	}
+	return fsc.deadLetters.Get(id)
+}
+
+//ReplayDeadLetter delivers a dead lettered event again to the sink or handler it failed on.
+//On success the dead letter is removed, else its attempt count and error are updated.
+func (fsc *FabricSDKClient) ReplayDeadLetter(id string) error {
+	letter, err := fsc.GetDeadLetter(id)
+	if err != nil {
+		return err
+	}
+	sink := fsc.sinkByName(letter.Sink)
+	if sink == nil {
+		return fmt.Errorf("Sink %s of dead letter %s is not registered", letter.Sink, id)
+	}
+	if err := sink.Send(letter.Event); err != nil {
+		letter.Attempts += deliveryAttempts(err)
+		letter.Error = err.Error()
+		letter.LastFailedAt = time.Now()
+		if updateErr := fsc.deadLetters.Update(letter); updateErr != nil {
+			_logger.Errorf("Unable to update dead letter %s %+v", id, updateErr)
+		}
+		return fmt.Errorf("Replay of dead letter %s failed: %v", id, err)
+	}
+	_logger.Infof("Dead letter %s replayed to %s", id, letter.Sink)
+	return fsc.deadLetters.Remove(id)
+}
+
+//ReplayDeadLetters replays every dead letter and returns the number of successful replays.
+//The replay continues after a failure, the last error is returned.
+func (fsc *FabricSDKClient) ReplayDeadLetters() (int, error) {
+	letters, err := fsc.ListDeadLetters()
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	var lastErr error
+	for _, letter := range letters {
+		if err := fsc.ReplayDeadLetter(letter.ID); err != nil {
+			lastErr = err
+			continue
+		}
+		replayed++
+	}
+	return replayed, lastErr
+}
+
+//PurgeDeadLetters deletes the given dead letters or all of them if no ID is given
+func (fsc *FabricSDKClient) PurgeDeadLetters(ids ...string) (int, error) {
+	if fsc.deadLetters == nil {
+		return 0, nil
+	}
+	return fsc.deadLetters.Purge(ids...)
+}
@@ -0,0 +1,99 @@
+package fabricgosdkclientcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+//DefaultEventCheckpointPath is used when event sinks are configured without x-eventCheckpoint
+const DefaultEventCheckpointPath = "./tmp/event-checkpoints.json"
+
+//EventCheckpointStore keeps the last block number delivered for each event subscription.
+//The checkpoints are saved in a single JSON file so that a restarted client resumes
+//the subscriptions from where it stopped.
+type EventCheckpointStore struct {
+	path        string
+	lock        sync.Mutex
+	checkpoints map[string]uint64
+	dirty       bool
+}
+
+//NewEventCheckpointStore creates a checkpoint store backed by the file in path.
+//Existing checkpoints in the file are loaded.
+func NewEventCheckpointStore(path string) (*EventCheckpointStore, error) {
+	store := &EventCheckpointStore{path: path, checkpoints: make(map[string]uint64)}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("Unable to read checkpoint file %s: %v", path, err)
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &store.checkpoints); err != nil {
+			return nil, fmt.Errorf("Invalid checkpoint file %s: %v", path, err)
+		}
+	}
+	return store, nil
+}
+
+//Get returns the last block delivered for the subscription
+func (ecs *EventCheckpointStore) Get(subscription string) (uint64, bool) {
+	ecs.lock.Lock()
+	defer ecs.lock.Unlock()
+	blockNumber, isFound := ecs.checkpoints[subscription]
+	return blockNumber, isFound
+}
+
+//Update moves the checkpoint of the subscription forward and saves it.
+//Block numbers lower than the current checkpoint are ignored.
+func (ecs *EventCheckpointStore) Update(subscription string, blockNumber uint64) error {
+	ecs.lock.Lock()
+	if current, isFound := ecs.checkpoints[subscription]; isFound && current >= blockNumber {
+		ecs.lock.Unlock()
+		return nil
+	}
+	ecs.checkpoints[subscription] = blockNumber
+	ecs.dirty = true
+	ecs.lock.Unlock()
+	return ecs.Flush()
+}
+
+//Flush writes the pending checkpoints to the file
+func (ecs *EventCheckpointStore) Flush() error {
+	ecs.lock.Lock()
+	defer ecs.lock.Unlock()
+	if !ecs.dirty {
+		return nil
+	}
+	content, err := json.MarshalIndent(ecs.checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(ecs.path, content); err != nil {
+		return fmt.Errorf("Unable to save checkpoint file %s: %v", ecs.path, err)
+	}
+	ecs.dirty = false
+	return nil
+}
+
+//writeFileAtomic writes the content in a temporary file and renames it to path
+func writeFileAtomic(path string, content []byte) error {
+	return writeFileAtomicMode(path, content, 0644)
+}
+
+//writeFileAtomicMode is writeFileAtomic with the permissions of the file
+func writeFileAtomicMode(path string, content []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, content, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
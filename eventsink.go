@@ -0,0 +1,447 @@
+package fabricgosdkclientcore
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/options"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fab/events/deliverclient"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fab/events/deliverclient/seek"
+)
+
+//Event types forwarded to the event sinks
+const (
+	SinkEventTypeCC    = "CCEVENT"
+	SinkEventTypeBlock = "BLOCK"
+)
+
+//SinkEvent is the form in which chain code and block events are handed over to the event sinks.
+//For block events the payload holds the protobuf encoded block.
+type SinkEvent struct {
+	Subscription string    `json:"subscription"`
+	Type         string    `json:"type"`
+	ChannelID    string    `json:"channelId"`
+	ChaincodeID  string    `json:"chaincodeId,omitempty"`
+	EventName    string    `json:"eventName,omitempty"`
+	TxID         string    `json:"txId,omitempty"`
+	BlockNumber  uint64    `json:"blockNumber"`
+	Payload      []byte    `json:"payload,omitempty"`
+	SourceURL    string    `json:"sourceUrl,omitempty"`
+	ReceivedAt   time.Time `json:"receivedAt"`
+}
+
+//EventSink receives the events of the subscriptions it is configured for.
+//Send must return only after the event is durably handed over, the event checkpoint
+//is moved forward on its success.
+type EventSink interface {
+	Name() string
+	Send(event *SinkEvent) error
+	Flush() error
+	Close() error
+}
+
+//SinkDeliveryError is the error of a sink which tried the delivery more than once
+type SinkDeliveryError struct {
+	Attempts int
+	Err      error
+}
+
+func (sde *SinkDeliveryError) Error() string {
+	return sde.Err.Error()
+}
+
+//deliveryAttempts returns the number of deliveries tried by a failed Send
+func deliveryAttempts(sendErr error) int {
+	if deliveryErr, isDeliveryErr := sendErr.(*SinkDeliveryError); isDeliveryErr && deliveryErr.Attempts > 0 {
+		return deliveryErr.Attempts
+	}
+	return 1
+}
+
+//EventSinkConfig is one entry of the x-eventSinks list of the client configuration.
+//Channel, Chaincode and Events restrict the subscriptions forwarded to the sink, an empty
+//value matches everything.
+type EventSinkConfig struct {
+	Name      string `mapstructure:"name"`
+	Type      string `mapstructure:"type"`
+	Channel   string `mapstructure:"channel"`
+	Chaincode string `mapstructure:"chaincode"`
+	Events    string `mapstructure:"events"`
+
+	//file sink
+	Path        string `mapstructure:"path"`
+	MaxFileSize int64  `mapstructure:"maxFileSize"`
+	MaxFiles    int    `mapstructure:"maxFiles"`
+
+	//webhook sink
+	URL           string        `mapstructure:"url"`
+	Secret        string        `mapstructure:"secret"`
+	MaxRetries    int           `mapstructure:"maxRetries"`
+	RetryInterval time.Duration `mapstructure:"retryInterval"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+
+	//nats sink, URL is shared with the webhook sink
+	Subject string `mapstructure:"subject"`
+}
+
+//EventCheckpointConfig is the x-eventCheckpoint entry of the client configuration
+type EventCheckpointConfig struct {
+	Path string `mapstructure:"path"`
+}
+
+//NewEventSink creates an event sink of the type given in the configuration
+func NewEventSink(config EventSinkConfig) (EventSink, error) {
+	if len(config.Name) == 0 {
+		return nil, fmt.Errorf("Event sink name is missing")
+	}
+	switch strings.ToLower(config.Type) {
+	case "file":
+		return NewFileEventSink(config)
+	case "webhook":
+		return NewWebhookEventSink(config)
+	case "nats":
+		return NewNATSEventSink(config)
+	}
+	return nil, fmt.Errorf("Unknown event sink type %s for sink %s", config.Type, config.Name)
+}
+
+//matches returns true if the sink is configured for events of the given type, channel and chain code
+func (esc *EventSinkConfig) matches(evtType, channelID, ccID string) bool {
+	if len(esc.Events) > 0 && !strings.EqualFold(esc.Events, evtType) {
+		return false
+	}
+	if len(esc.Channel) > 0 && esc.Channel != channelID {
+		return false
+	}
+	if len(esc.Chaincode) > 0 && esc.Chaincode != ccID {
+		return false
+	}
+	return true
+}
+
+type configuredSink struct {
+	config EventSinkConfig
+	sink   EventSink
+}
+
+//AddEventSink creates and adds an event sink in addition to the ones in x-eventSinks.
+//It applies to the subscriptions registered afterwards.
+func (fsc *FabricSDKClient) AddEventSink(config EventSinkConfig) error {
+	fsc.eventSinksLock.Lock()
+	defer fsc.eventSinksLock.Unlock()
+	for _, existing := range fsc.eventSinks {
+		if existing.config.Name == config.Name {
+			return fmt.Errorf("Event sink %s already exists", config.Name)
+		}
+	}
+	sink, err := NewEventSink(config)
+	if err != nil {
+		return err
+	}
+	if err := fsc.initEventStores(); err != nil {
+		sink.Close()
+		return err
+	}
+	fsc.eventSinks = append(fsc.eventSinks, configuredSink{config: config, sink: sink})
+	_logger.Infof("Event sink %s of type %s added", config.Name, config.Type)
+	return nil
+}
+
+//initEventStores creates the checkpoint and dead letter stores with the default paths if
+//they are not configured
+func (fsc *FabricSDKClient) initEventStores() error {
+	var err error
+	if fsc.checkpoints == nil {
+		if fsc.checkpoints, err = NewEventCheckpointStore(DefaultEventCheckpointPath); err != nil {
+			return err
+		}
+	}
+	if fsc.deadLetters == nil {
+		if fsc.deadLetters, err = NewDeadLetterStore(DefaultDeadLetterPath, defaultDeadLetterMaxEntries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//loadEventSinks creates the sinks, the checkpoint store and the dead letter store from the configuration
+func (fsc *FabricSDKClient) loadEventSinks(checkpointConfig EventCheckpointConfig, deadLetterConfig DeadLetterConfig, sinkConfigs []EventSinkConfig) bool {
+	if len(checkpointConfig.Path) > 0 {
+		checkpoints, err := NewEventCheckpointStore(checkpointConfig.Path)
+		if err != nil {
+			_logger.Errorf("Error in loading event checkpoints %+v", err)
+			return false
+		}
+		fsc.checkpoints = checkpoints
+	}
+	if len(deadLetterConfig.Path) > 0 {
+		deadLetters, err := NewDeadLetterStore(deadLetterConfig.Path, deadLetterConfig.MaxEntries)
+		if err != nil {
+			_logger.Errorf("Error in loading dead letter store %+v", err)
+			return false
+		}
+		fsc.deadLetters = deadLetters
+	}
+	for _, sinkConfig := range sinkConfigs {
+		if err := fsc.AddEventSink(sinkConfig); err != nil {
+			_logger.Errorf("Error in creating event sink %s %+v", sinkConfig.Name, err)
+			return false
+		}
+	}
+	return true
+}
+
+//sinksFor returns the sinks configured for a subscription
+func (fsc *FabricSDKClient) sinksFor(evtType, channelID, ccID string) []EventSink {
+	fsc.eventSinksLock.RLock()
+	defer fsc.eventSinksLock.RUnlock()
+	sinks := make([]EventSink, 0)
+	for _, configured := range fsc.eventSinks {
+		if configured.config.matches(evtType, channelID, ccID) {
+			sinks = append(sinks, configured.sink)
+		}
+	}
+	return sinks
+}
+
+//sinkByName returns a configured sink or a registered event handler by its name
+func (fsc *FabricSDKClient) sinkByName(name string) EventSink {
+	fsc.eventSinksLock.RLock()
+	defer fsc.eventSinksLock.RUnlock()
+	for _, configured := range fsc.eventSinks {
+		if configured.config.Name == name {
+			return configured.sink
+		}
+	}
+	if handler, isFound := fsc.eventHandlers[name]; isFound {
+		return handler
+	}
+	return nil
+}
+
+//eventServiceOptions returns the event service options of a subscription. If the subscription
+//has a checkpoint the events are replayed from the checkpoint block onwards.
+func (fsc *FabricSDKClient) eventServiceOptions(subscription string, sinks []EventSink) []options.Opt {
+	if len(sinks) == 0 || fsc.checkpoints == nil {
+		return nil
+	}
+	if blockNumber, isFound := fsc.checkpoints.Get(subscription); isFound {
+		_logger.Infof("Resuming %s from block %d", subscription, blockNumber)
+		return []options.Opt{deliverclient.WithSeekType(seek.FromBlock), deliverclient.WithBlockNum(blockNumber)}
+	}
+	return nil
+}
+
+//closeEventSinks flushes the checkpoints and closes all sinks
+func (fsc *FabricSDKClient) closeEventSinks() {
+	fsc.eventSinksLock.Lock()
+	sinks := fsc.eventSinks
+	fsc.eventSinks = nil
+	fsc.eventSinksLock.Unlock()
+	for _, configured := range sinks {
+		if err := configured.sink.Close(); err != nil {
+			_logger.Errorf("Error in closing event sink %s %+v", configured.config.Name, err)
+		}
+	}
+	if fsc.checkpoints != nil {
+		if err := fsc.checkpoints.Flush(); err != nil {
+			_logger.Errorf("Error in saving event checkpoints %+v", err)
+		}
+	}
+}
+
+//eventForwarder delivers the events of one subscription to its sinks. An event a sink fails on
+//is put in the dead letter store. The checkpoint is moved only when every sink accepted the event
+//or it is dead lettered, otherwise the checkpoint stays where it is so that the undelivered
+//events are replayed after a restart.
+type eventForwarder struct {
+	subscription string
+	sinks        []EventSink
+	checkpoints  *EventCheckpointStore
+	deadLetters  *DeadLetterStore
+	running      *sync.WaitGroup
+	stalled      bool
+}
+
+//newEventForwarder creates the forwarder of a subscription. Shutdown waits for its goroutine.
+func (fsc *FabricSDKClient) newEventForwarder(subscription string, sinks []EventSink) *eventForwarder {
+	return &eventForwarder{subscription: subscription, sinks: sinks, checkpoints: fsc.checkpoints, deadLetters: fsc.deadLetters, running: &fsc.forwarders}
+}
+
+func (ef *eventForwarder) forward(event *SinkEvent) {
+	delivered := true
+	for _, sink := range ef.sinks {
+		if err := sink.Send(event); err != nil {
+			_logger.Errorf("Event sink %s failed for %s block %d: %+v", sink.Name(), ef.subscription, event.BlockNumber, err)
+			if !ef.deadLetter(sink, event, err) {
+				delivered = false
+			}
+		}
+	}
+	if !delivered {
+		if !ef.stalled {
+			_logger.Warningf("Checkpoint of %s is held at the last delivered block", ef.subscription)
+		}
+		ef.stalled = true
+		return
+	}
+	if ef.stalled || ef.checkpoints == nil {
+		return
+	}
+	if err := ef.checkpoints.Update(ef.subscription, event.BlockNumber); err != nil {
+		_logger.Errorf("Error in saving checkpoint of %s %+v", ef.subscription, err)
+	}
+}
+
+//deadLetter saves the failed event in the dead letter store
+func (ef *eventForwarder) deadLetter(sink EventSink, event *SinkEvent, sendErr error) bool {
+	if ef.deadLetters == nil {
+		return false
+	}
+	now := time.Now()
+	letter := &DeadLetter{
+		Subscription:  ef.subscription,
+		Sink:          sink.Name(),
+		Event:         event,
+		Error:         sendErr.Error(),
+		Attempts:      deliveryAttempts(sendErr),
+		FirstFailedAt: now,
+		LastFailedAt:  now,
+	}
+	if err := ef.deadLetters.Add(letter); err != nil {
+		_logger.Errorf("Unable to dead letter event of %s for sink %s %+v", ef.subscription, sink.Name(), err)
+		return false
+	}
+	_logger.Warningf("Event of %s block %d dead lettered as %s", ef.subscription, event.BlockNumber, letter.ID)
+	return true
+}
+
+//forwardCCEvents forwards chain code events to the sinks. The returned channel passes the
+//events on to the listener, nil is returned when there is no listener.
+func (ef *eventForwarder) forwardCCEvents(channelID string, input <-chan *fab.CCEvent, hasListener bool) <-chan *fab.CCEvent {
+	var output chan *fab.CCEvent
+	if hasListener {
+		output = make(chan *fab.CCEvent)
+	}
+	ef.running.Add(1)
+	go func() {
+		defer ef.running.Done()
+		if output != nil {
+			defer close(output)
+		}
+		for event := range input {
+			ef.forward(&SinkEvent{
+				Subscription: ef.subscription,
+				Type:         SinkEventTypeCC,
+				ChannelID:    channelID,
+				ChaincodeID:  event.ChaincodeID,
+				EventName:    event.EventName,
+				TxID:         event.TxID,
+				BlockNumber:  event.BlockNumber,
+				Payload:      event.Payload,
+				SourceURL:    event.SourceURL,
+				ReceivedAt:   time.Now(),
+			})
+			if output != nil {
+				output <- event
+			}
+		}
+	}()
+	return output
+}
+
+//forwardBlockEvents forwards block events to the sinks. The returned channel passes the
+//events on to the listener, nil is returned when there is no listener.
+func (ef *eventForwarder) forwardBlockEvents(channelID string, input <-chan *fab.BlockEvent, hasListener bool) <-chan *fab.BlockEvent {
+	var output chan *fab.BlockEvent
+	if hasListener {
+		output = make(chan *fab.BlockEvent)
+	}
+	ef.running.Add(1)
+	go func() {
+		defer ef.running.Done()
+		if output != nil {
+			defer close(output)
+		}
+		for event := range input {
+			if sinkEvent, err := newBlockSinkEvent(ef.subscription, channelID, event); err != nil {
+				_logger.Errorf("Block event of %s could not be forwarded %+v", ef.subscription, err)
+			} else {
+				ef.forward(sinkEvent)
+			}
+			if output != nil {
+				output <- event
+			}
+		}
+	}()
+	return output
+}
+
+func newBlockSinkEvent(subscription, channelID string, event *fab.BlockEvent) (*SinkEvent, error) {
+	if event.Block == nil || event.Block.Header == nil {
+		return nil, fmt.Errorf("Block event without block")
+	}
+	blockBytes, err := proto.Marshal(event.Block)
+	if err != nil {
+		return nil, err
+	}
+	return &SinkEvent{
+		Subscription: subscription,
+		Type:         SinkEventTypeBlock,
+		ChannelID:    channelID,
+		BlockNumber:  event.Block.Header.Number,
+		Payload:      blockBytes,
+		SourceURL:    event.SourceURL,
+		ReceivedAt:   time.Now(),
+	}, nil
+}
+
+//CCEventHandler handles one chain code event. An event for which the handler returns an
+//error is put in the dead letter store and can be replayed with ReplayDeadLetter.
+type CCEventHandler func(event *fab.CCEvent) error
+
+//eventHandlerName is the sink name of the handler of a chain code event subscription, the dead
+//letters of the handler are replayed to the sink of this name
+func eventHandlerName(eventName string) string {
+	return "handler:" + eventName
+}
+
+//removeEventHandler forgets the handler of a deregistered subscription
+func (fsc *FabricSDKClient) removeEventHandler(eventName string) {
+	fsc.eventSinksLock.Lock()
+	defer fsc.eventSinksLock.Unlock()
+	delete(fsc.eventHandlers, eventHandlerName(eventName))
+}
+
+//handlerSink adapts a CCEventHandler to the EventSink interface
+type handlerSink struct {
+	name    string
+	handler CCEventHandler
+}
+
+func (hs *handlerSink) Name() string {
+	return hs.name
+}
+
+func (hs *handlerSink) Send(event *SinkEvent) error {
+	return hs.handler(&fab.CCEvent{
+		TxID:        event.TxID,
+		ChaincodeID: event.ChaincodeID,
+		EventName:   event.EventName,
+		Payload:     event.Payload,
+		BlockNumber: event.BlockNumber,
+		SourceURL:   event.SourceURL,
+	})
+}
+
+func (hs *handlerSink) Flush() error {
+	return nil
+}
+
+func (hs *handlerSink) Close() error {
+	return nil
+}
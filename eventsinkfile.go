@@ -0,0 +1,153 @@
+package fabricgosdkclientcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSinkMaxFileSize = 10 * 1024 * 1024
+	defaultSinkMaxFiles    = 10
+)
+
+//FileEventSink writes the events as JSON lines in <path>/<name>.jsonl. When the file grows
+//beyond maxFileSize it is renamed with a timestamp suffix and a new file is started, only the
+//latest maxFiles rolled files are kept.
+type FileEventSink struct {
+	name        string
+	dir         string
+	maxFileSize int64
+	maxFiles    int
+	lock        sync.Mutex
+	file        *os.File
+	size        int64
+}
+
+//NewFileEventSink creates a file event sink
+func NewFileEventSink(config EventSinkConfig) (*FileEventSink, error) {
+	if len(config.Path) == 0 {
+		return nil, fmt.Errorf("Path is missing for file event sink %s", config.Name)
+	}
+	sink := &FileEventSink{name: config.Name, dir: config.Path, maxFileSize: config.MaxFileSize, maxFiles: config.MaxFiles}
+	if sink.maxFileSize <= 0 {
+		sink.maxFileSize = defaultSinkMaxFileSize
+	}
+	if sink.maxFiles <= 0 {
+		sink.maxFiles = defaultSinkMaxFiles
+	}
+	if err := os.MkdirAll(sink.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+//Name returns the sink name
+func (fes *FileEventSink) Name() string {
+	return fes.name
+}
+
+//Send appends the event to the current file and syncs it to the disk
+func (fes *FileEventSink) Send(event *SinkEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	fes.lock.Lock()
+	defer fes.lock.Unlock()
+	if fes.file == nil {
+		return fmt.Errorf("File event sink %s is closed", fes.name)
+	}
+	if fes.size > 0 && fes.size+int64(len(line)) > fes.maxFileSize {
+		if err := fes.roll(); err != nil {
+			return err
+		}
+	}
+	written, err := fes.file.Write(line)
+	fes.size += int64(written)
+	if err != nil {
+		return err
+	}
+	return fes.file.Sync()
+}
+
+//Flush syncs the current file
+func (fes *FileEventSink) Flush() error {
+	fes.lock.Lock()
+	defer fes.lock.Unlock()
+	if fes.file == nil {
+		return nil
+	}
+	return fes.file.Sync()
+}
+
+//Close closes the current file
+func (fes *FileEventSink) Close() error {
+	fes.lock.Lock()
+	defer fes.lock.Unlock()
+	if fes.file == nil {
+		return nil
+	}
+	err := fes.file.Close()
+	fes.file = nil
+	return err
+}
+
+func (fes *FileEventSink) currentPath() string {
+	return filepath.Join(fes.dir, fes.name+".jsonl")
+}
+
+func (fes *FileEventSink) open() error {
+	file, err := os.OpenFile(fes.currentPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	fes.file = file
+	fes.size = info.Size()
+	return nil
+}
+
+//roll renames the current file and removes the oldest rolled files
+func (fes *FileEventSink) roll() error {
+	if err := fes.file.Close(); err != nil {
+		return err
+	}
+	fes.file = nil
+	rolledPath := filepath.Join(fes.dir, fmt.Sprintf("%s-%s.jsonl", fes.name, time.Now().UTC().Format("20060102T150405.000000000")))
+	if err := os.Rename(fes.currentPath(), rolledPath); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(fes.dir)
+	if err != nil {
+		return err
+	}
+	rolledFiles := make([]string, 0)
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), fes.name+"-") && strings.HasSuffix(file.Name(), ".jsonl") {
+			rolledFiles = append(rolledFiles, file.Name())
+		}
+	}
+	sort.Strings(rolledFiles)
+	for len(rolledFiles) > fes.maxFiles {
+		if err := os.Remove(filepath.Join(fes.dir, rolledFiles[0])); err != nil {
+			_logger.Errorf("Unable to remove rolled event file %s %+v", rolledFiles[0], err)
+		}
+		rolledFiles = rolledFiles[1:]
+	}
+	return fes.open()
+}
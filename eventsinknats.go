@@ -0,0 +1,83 @@
+package fabricgosdkclientcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+const defaultNATSFlushTimeout = 5 * time.Second
+
+//NATSEventSink publishes the events as JSON to a NATS subject. The subject may contain the
+//placeholders {channel}, {chaincode}, {event} and {type}. Each publish is flushed so that the
+//server has the event before the checkpoint moves.
+type NATSEventSink struct {
+	name    string
+	subject string
+	timeout time.Duration
+	conn    *nats.Conn
+}
+
+//NewNATSEventSink connects to the NATS server and creates the sink
+func NewNATSEventSink(config EventSinkConfig) (*NATSEventSink, error) {
+	if len(config.Subject) == 0 {
+		return nil, fmt.Errorf("Subject is missing for nats event sink %s", config.Name)
+	}
+	url := config.URL
+	if len(url) == 0 {
+		url = nats.DefaultURL
+	}
+	conn, err := nats.Connect(url, nats.Name(config.Name))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to nats server %s: %v", url, err)
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultNATSFlushTimeout
+	}
+	return &NATSEventSink{name: config.Name, subject: config.Subject, timeout: timeout, conn: conn}, nil
+}
+
+//Name returns the sink name
+func (nes *NATSEventSink) Name() string {
+	return nes.name
+}
+
+//Send publishes the event and waits for the server to acknowledge the flush
+func (nes *NATSEventSink) Send(event *SinkEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := nes.conn.Publish(nes.subjectFor(event), data); err != nil {
+		return err
+	}
+	return nes.conn.FlushTimeout(nes.timeout)
+}
+
+func (nes *NATSEventSink) subjectFor(event *SinkEvent) string {
+	replacer := strings.NewReplacer(
+		"{channel}", event.ChannelID,
+		"{chaincode}", event.ChaincodeID,
+		"{event}", event.EventName,
+		"{type}", strings.ToLower(event.Type))
+	return replacer.Replace(nes.subject)
+}
+
+//Flush flushes the connection
+func (nes *NATSEventSink) Flush() error {
+	return nes.conn.FlushTimeout(nes.timeout)
+}
+
+//Close flushes and closes the connection
+func (nes *NATSEventSink) Close() error {
+	if nes.conn.IsClosed() {
+		return nil
+	}
+	err := nes.conn.FlushTimeout(nes.timeout)
+	nes.conn.Close()
+	return err
+}
@@ -0,0 +1,129 @@
+package fabricgosdkclientcore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultWebhookMaxRetries    = 3
+	defaultWebhookRetryInterval = time.Second
+	defaultWebhookTimeout       = 10 * time.Second
+
+	//WebhookSignatureHeader carries the hex encoded HMAC-SHA256 of the request body
+	WebhookSignatureHeader = "X-Fabric-Signature"
+	//WebhookSubscriptionHeader carries the name of the subscription the event belongs to
+	WebhookSubscriptionHeader = "X-Fabric-Subscription"
+)
+
+//WebhookEventSink posts each event as JSON to a URL. When a secret is configured the body
+//is signed with HMAC-SHA256 and the signature is sent in the X-Fabric-Signature header.
+//Failed posts are retried with a doubling interval.
+type WebhookEventSink struct {
+	name          string
+	url           string
+	secret        []byte
+	maxRetries    int
+	retryInterval time.Duration
+	client        *http.Client
+}
+
+//NewWebhookEventSink creates a webhook event sink
+func NewWebhookEventSink(config EventSinkConfig) (*WebhookEventSink, error) {
+	if len(config.URL) == 0 {
+		return nil, fmt.Errorf("URL is missing for webhook event sink %s", config.Name)
+	}
+	sink := &WebhookEventSink{
+		name:          config.Name,
+		url:           config.URL,
+		secret:        []byte(config.Secret),
+		maxRetries:    config.MaxRetries,
+		retryInterval: config.RetryInterval,
+	}
+	if sink.maxRetries <= 0 {
+		sink.maxRetries = defaultWebhookMaxRetries
+	}
+	if sink.retryInterval <= 0 {
+		sink.retryInterval = defaultWebhookRetryInterval
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	sink.client = &http.Client{Timeout: timeout}
+	return sink, nil
+}
+
+//Name returns the sink name
+func (wes *WebhookEventSink) Name() string {
+	return wes.name
+}
+
+//Send posts the event and retries until a 2xx response is received or the retries are exhausted
+func (wes *WebhookEventSink) Send(event *SinkEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	interval := wes.retryInterval
+	for attempt := 0; ; attempt++ {
+		err = wes.post(event, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= wes.maxRetries {
+			return &SinkDeliveryError{Attempts: attempt + 1, Err: fmt.Errorf("Webhook %s failed after %d attempts: %v", wes.name, attempt+1, err)}
+		}
+		_logger.Warningf("Webhook %s attempt %d failed, retrying in %s: %v", wes.name, attempt+1, interval, err)
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+func (wes *WebhookEventSink) post(event *SinkEvent, body []byte) error {
+	postReq, err := http.NewRequest("POST", wes.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	postReq.Header.Set("Content-Type", "application/json")
+	postReq.Header.Set(WebhookSubscriptionHeader, event.Subscription)
+	if len(wes.secret) > 0 {
+		postReq.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookBody(wes.secret, body))
+	}
+	resp, err := wes.client.Do(postReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+//Flush is a no-op for the webhook sink, each event is posted synchronously
+func (wes *WebhookEventSink) Flush() error {
+	return nil
+}
+
+//Close is a no-op for the webhook sink
+func (wes *WebhookEventSink) Close() error {
+	return nil
+}
+
+//SignWebhookBody returns the hex encoded HMAC-SHA256 of the body. Receivers can use it to
+//verify the X-Fabric-Signature header.
+func SignWebhookBody(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,356 @@
+package fabricgosdkclientcore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	core "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/core"
+	msp "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/msp"
+	lookup "github.com/hyperledger/fabric-sdk-go/pkg/core/config/lookup"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk/factory/defcore"
+	mspprotos "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+)
+
+//ExternalSigner signs SHA-256 digests with a private key held outside the client, for example
+//in a signing service or a KMS. The signature is an ASN.1 DER encoded ECDSA signature.
+type ExternalSigner interface {
+	Sign(keyID string, digest []byte) ([]byte, error)
+}
+
+//ExternalSignerConfig is the x-externalSigner configuration of the x-remote-admin signer
+type ExternalSignerConfig struct {
+	URL       string `json:"url"`
+	KeyID     string `json:"keyId"`
+	AuthToken string `json:"authToken"`
+	CertPath  string `json:"certPath"`
+}
+
+//externalSignRequest and externalSignResponse are the JSON messages of the HTTP signing service
+type externalSignRequest struct {
+	KeyID  string `json:"keyId"`
+	Digest []byte `json:"digest"`
+}
+
+type externalSignResponse struct {
+	Signature []byte `json:"signature"`
+}
+
+//HTTPExternalSigner posts the digests to a signing service. The digest and the signature are
+//base64 encoded in the JSON messages.
+type HTTPExternalSigner struct {
+	URL       string
+	AuthToken string
+	Client    *http.Client
+}
+
+//NewHTTPExternalSigner creates a signer posting to the signing service URL
+func NewHTTPExternalSigner(url, authToken string) *HTTPExternalSigner {
+	return &HTTPExternalSigner{URL: url, AuthToken: authToken, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+//Sign asks the signing service for the signature of the digest
+func (hes *HTTPExternalSigner) Sign(keyID string, digest []byte) ([]byte, error) {
+	requestBody, err := json.Marshal(externalSignRequest{KeyID: keyID, Digest: digest})
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, hes.URL, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(hes.AuthToken) > 0 {
+		request.Header.Set("Authorization", "Bearer "+hes.AuthToken)
+	}
+	response, err := hes.Client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("Signing service %s unreachable: %v", hes.URL, err)
+	}
+	defer response.Body.Close()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Signing service returned %s: %s", response.Status, responseBody)
+	}
+	signResponse := new(externalSignResponse)
+	if err := json.Unmarshal(responseBody, signResponse); err != nil {
+		return nil, fmt.Errorf("Invalid signing service response: %v", err)
+	}
+	return signResponse.Signature, nil
+}
+
+//LocalExternalSigner signs with keys held in memory. It stands in for a signing service in tests
+//and serves the protocol of HTTPExternalSigner.
+type LocalExternalSigner struct {
+	keys map[string]*ecdsa.PrivateKey
+	lock sync.RWMutex
+}
+
+//NewLocalExternalSigner creates a signer without keys
+func NewLocalExternalSigner() *LocalExternalSigner {
+	return &LocalExternalSigner{keys: make(map[string]*ecdsa.PrivateKey)}
+}
+
+//AddKey adds a PEM encoded private key under keyID
+func (les *LocalExternalSigner) AddKey(keyID string, keyPEM []byte) error {
+	privateKey, err := parsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return err
+	}
+	les.lock.Lock()
+	defer les.lock.Unlock()
+	les.keys[keyID] = privateKey
+	return nil
+}
+
+//Sign signs the digest with the key
+func (les *LocalExternalSigner) Sign(keyID string, digest []byte) ([]byte, error) {
+	les.lock.RLock()
+	privateKey, isFound := les.keys[keyID]
+	les.lock.RUnlock()
+	if !isFound {
+		return nil, fmt.Errorf("Unknown key %s", keyID)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ecdsaSignature{R: r, S: s})
+}
+
+//ServeHTTP answers the sign requests of HTTPExternalSigner
+func (les *LocalExternalSigner) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	signRequest := new(externalSignRequest)
+	if err := json.NewDecoder(request.Body).Decode(signRequest); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	signature, err := les.Sign(signRequest.KeyID, signRequest.Digest)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusNotFound)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(externalSignResponse{Signature: signature})
+}
+
+//toLowS rewrites an ECDSA signature with the low S value the peers and orderers require
+func toLowS(publicKey *ecdsa.PublicKey, signature []byte) ([]byte, error) {
+	ecdsaSig := new(ecdsaSignature)
+	if _, err := asn1.Unmarshal(signature, ecdsaSig); err != nil {
+		return nil, fmt.Errorf("Invalid signature encoding: %v", err)
+	}
+	curveOrder := publicKey.Curve.Params().N
+	if ecdsaSig.S.Cmp(new(big.Int).Rsh(curveOrder, 1)) <= 0 {
+		return signature, nil
+	}
+	ecdsaSig.S = new(big.Int).Sub(curveOrder, ecdsaSig.S)
+	return asn1.Marshal(*ecdsaSig)
+}
+
+//externalKey is the private key of an external signer as seen by the SDK. Only its SKI and
+//public key are known to the client.
+type externalKey struct {
+	keyID     string
+	signer    ExternalSigner
+	publicKey *ecdsa.PublicKey
+}
+
+func (ek *externalKey) Bytes() ([]byte, error) {
+	return nil, fmt.Errorf("Private key %s is held by the external signer", ek.keyID)
+}
+
+func (ek *externalKey) SKI() []byte {
+	hash := sha256.Sum256(elliptic.Marshal(ek.publicKey.Curve, ek.publicKey.X, ek.publicKey.Y))
+	return hash[:]
+}
+
+func (ek *externalKey) Symmetric() bool {
+	return false
+}
+
+func (ek *externalKey) Private() bool {
+	return true
+}
+
+func (ek *externalKey) PublicKey() (core.Key, error) {
+	return &externalPublicKey{externalKey: ek}, nil
+}
+
+//sign signs the SHA-256 digest of the message with the external signer
+func (ek *externalKey) sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	signature, err := ek.signer.Sign(ek.keyID, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("External signature with %s failed: %v", ek.keyID, err)
+	}
+	return toLowS(ek.publicKey, signature)
+}
+
+type externalPublicKey struct {
+	*externalKey
+}
+
+func (epk *externalPublicKey) Bytes() ([]byte, error) {
+	return elliptic.Marshal(epk.publicKey.Curve, epk.publicKey.X, epk.publicKey.Y), nil
+}
+
+func (epk *externalPublicKey) Private() bool {
+	return false
+}
+
+//ExternalSigningIdentity is a signing identity whose private key is held by an external signer
+type ExternalSigningIdentity struct {
+	identifier *msp.IdentityIdentifier
+	certPEM    []byte
+	key        *externalKey
+}
+
+//NewExternalSigningIdentity creates the identity of a certificate signed for by the external signer
+//with the key keyID
+func NewExternalSigningIdentity(userID, mspID string, certPEM []byte, signer ExternalSigner, keyID string) (*ExternalSigningIdentity, error) {
+	certificate, err := parsePEMCertificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, isEC := certificate.PublicKey.(*ecdsa.PublicKey)
+	if !isEC {
+		return nil, fmt.Errorf("Certificate of %s has no ECDSA public key", userID)
+	}
+	return &ExternalSigningIdentity{
+		identifier: &msp.IdentityIdentifier{ID: userID, MSPID: mspID},
+		certPEM:    certPEM,
+		key:        &externalKey{keyID: keyID, signer: signer, publicKey: publicKey},
+	}, nil
+}
+
+//Identifier returns the user ID and MSP ID
+func (esi *ExternalSigningIdentity) Identifier() *msp.IdentityIdentifier {
+	return esi.identifier
+}
+
+//Verify checks a signature of the identity
+func (esi *ExternalSigningIdentity) Verify(message []byte, signature []byte) error {
+	ecdsaSig := new(ecdsaSignature)
+	if _, err := asn1.Unmarshal(signature, ecdsaSig); err != nil {
+		return fmt.Errorf("Invalid signature encoding: %v", err)
+	}
+	digest := sha256.Sum256(message)
+	if !ecdsa.Verify(esi.key.publicKey, digest[:], ecdsaSig.R, ecdsaSig.S) {
+		return fmt.Errorf("Signature of %s does not match", esi.identifier.ID)
+	}
+	return nil
+}
+
+//Serialize returns the serialized identity used as creator of the transactions
+func (esi *ExternalSigningIdentity) Serialize() ([]byte, error) {
+	return proto.Marshal(&mspprotos.SerializedIdentity{Mspid: esi.identifier.MSPID, IdBytes: esi.certPEM})
+}
+
+//EnrollmentCertificate returns the PEM encoded certificate
+func (esi *ExternalSigningIdentity) EnrollmentCertificate() []byte {
+	return esi.certPEM
+}
+
+//Sign signs the message with the external signer
+func (esi *ExternalSigningIdentity) Sign(message []byte) ([]byte, error) {
+	return esi.key.sign(message)
+}
+
+//PublicVersion returns the identity itself, it holds no private key
+func (esi *ExternalSigningIdentity) PublicVersion() msp.Identity {
+	return esi
+}
+
+//PrivateKey returns the handle of the external key given to the signing manager of the SDK
+func (esi *ExternalSigningIdentity) PrivateKey() core.Key {
+	return esi.key
+}
+
+//externalSigningManager sends the signatures with external keys to their signer and the others
+//to the signing manager of the SDK
+type externalSigningManager struct {
+	defaultManager core.SigningManager
+}
+
+func (esm *externalSigningManager) Sign(object []byte, key core.Key) ([]byte, error) {
+	if externalKey, isExternal := key.(*externalKey); isExternal {
+		return externalKey.sign(object)
+	}
+	return esm.defaultManager.Sign(object, key)
+}
+
+//externalSignerProviderFactory is the default core provider factory of the SDK with a signing
+//manager aware of the external keys
+type externalSignerProviderFactory struct {
+	*defcore.ProviderFactory
+}
+
+func newExternalSignerProviderFactory() *externalSignerProviderFactory {
+	return &externalSignerProviderFactory{ProviderFactory: defcore.NewProviderFactory()}
+}
+
+//CreateSigningManager wraps the signing manager of the SDK
+func (espf *externalSignerProviderFactory) CreateSigningManager(cryptoProvider core.CryptoSuite) (core.SigningManager, error) {
+	defaultManager, err := espf.ProviderFactory.CreateSigningManager(cryptoProvider)
+	if err != nil {
+		return nil, err
+	}
+	return &externalSigningManager{defaultManager: defaultManager}, nil
+}
+
+//UseExternalSigner makes the client sign as userID with the key keyID of the external signer,
+//the private key never reaches the client. The user can then be used as any other user,
+//including as x-remote-admin.
+func (fsc *FabricSDKClient) UseExternalSigner(userID, mspID string, certPEM []byte, signer ExternalSigner, keyID string) error {
+	identity, err := NewExternalSigningIdentity(userID, mspID, certPEM, signer, keyID)
+	if err != nil {
+		return err
+	}
+	fsc.clientsLock.Lock()
+	defer fsc.clientsLock.Unlock()
+	fsc.externalIdentities[userID] = identity
+	return nil
+}
+
+func (fsc *FabricSDKClient) externalIdentity(userID string) *ExternalSigningIdentity {
+	fsc.clientsLock.RLock()
+	defer fsc.clientsLock.RUnlock()
+	return fsc.externalIdentities[userID]
+}
+
+//loadExternalSigner signs as the remote admin with the signing service of the x-externalSigner
+//configuration
+func (fsc *FabricSDKClient) loadExternalSigner(cnfBackend core.ConfigBackend) bool {
+	var signerConfig ExternalSignerConfig
+	if err := lookup.New(cnfBackend).UnmarshalKey("x-externalSigner", &signerConfig); err != nil {
+		_logger.Errorf("Invalid x-externalSigner configuration %+v", err)
+		return false
+	}
+	certPEM, err := ioutil.ReadFile(signerConfig.CertPath)
+	if err != nil {
+		_logger.Errorf("Unable to read the remote admin certificate %+v", err)
+		return false
+	}
+	mspID, _ := cnfBackend.Lookup(fmt.Sprintf("organizations.%s.mspid", strings.ToLower(fsc.clientOrg)))
+	mspIDValue, _ := mspID.(string)
+	signer := NewHTTPExternalSigner(signerConfig.URL, signerConfig.AuthToken)
+	if err := fsc.UseExternalSigner(fsc.remoteAdminID, mspIDValue, certPEM, signer, signerConfig.KeyID); err != nil {
+		_logger.Errorf("Unable to use the external signer %+v", err)
+		return false
+	}
+	_logger.Infof("Remote admin %s signs with %s", fsc.remoteAdminID, signerConfig.URL)
+	return true
+}
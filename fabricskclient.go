@@ -9,6 +9,7 @@ import (
 	mspclient "github.com/hyperledger/fabric-sdk-go/pkg/client/msp"
 	resourceMgmnt "github.com/hyperledger/fabric-sdk-go/pkg/client/resmgmt"
 	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/retry"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/options"
 	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
 
 	ledger "github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
@@ -17,6 +18,7 @@ import (
 	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
 	msp "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/msp"
 	sdkConfig "github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	lookup "github.com/hyperledger/fabric-sdk-go/pkg/core/config/lookup"
 	packager "github.com/hyperledger/fabric-sdk-go/pkg/fab/ccpackager/gopackager"
 	eventClient "github.com/hyperledger/fabric-sdk-go/pkg/fab/events/client"
 	fabsdk "github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
@@ -48,12 +50,31 @@ type FabricSDKClient struct {
 	orgMSPClient   *mspclient.Client
 	remoteAdminID  string
 	isRemoteAdmin  bool
+	eventSinks     []configuredSink
+	eventHandlers  map[string]EventSink
+	eventSinksLock sync.RWMutex
+	checkpoints    *EventCheckpointStore
+	deadLetters    *DeadLetterStore
+	eventSubsLock  sync.Mutex
+	lifecycleLock  sync.Mutex
+	isShutdown     bool
+	inFlight       sync.WaitGroup
+	forwarders     sync.WaitGroup
+	clientsLock    sync.RWMutex
+	enrolledUsers  map[string]bool
+	certMonitor    chan struct{}
+	wallet         Wallet
+	//externalIdentities sign with keys held by an external signer
+	externalIdentities map[string]*ExternalSigningIdentity
+	//storeIdentityLock serializes the CA requests of users moved back from the wallet
+	storeIdentityLock sync.Mutex
+	//tlsEnrollment enrolls the TLS client certificate of the org admin when it is enrolled
+	tlsEnrollment *TLSEnrollment
 }
 
 //EventWaitGroup manages the event related wait groups
 type EventWaitGroup struct {
 	eventName    string
-	wg           *sync.WaitGroup
 	evtType      string
 	eventService fab.EventService
 	registration fab.Registration
@@ -62,11 +83,6 @@ type BlockEventListener func(<-chan *fab.BlockEvent, *sync.WaitGroup)
 type BlockWithTrxnEventListener func(<-chan *fab.FilteredBlockEvent, *sync.WaitGroup)
 type CCEventListener func(<-chan *fab.CCEvent, *sync.WaitGroup)
 
-//Shutdown Shutdown the client
-func (fsc *FabricSDKClient) Shutdown() {
-	defer fsc.sdk.Close()
-}
-
 //Init initializes the FabricSDK Client and its structure
 func (fsc *FabricSDKClient) Init(configPath string) bool {
 	//logging.SetLevel(logging.DEBUG, "fabric-sdk-client")
@@ -74,7 +90,7 @@ func (fsc *FabricSDKClient) Init(configPath string) bool {
 	fsc.configPath = configPath
 	fsc.configProvider = sdkConfig.FromFile(fsc.configPath)
 	//confBankEnds, _ := fsc.configProvider()
-	fsc.sdk, err = fabsdk.New(fsc.configProvider)
+	fsc.sdk, err = fabsdk.New(fsc.configProvider, fabsdk.WithCorePkg(newExternalSignerProviderFactory()))
 	if err != nil {
 		_logger.Errorf("Error in initialization of SDK %+v", err)
 		return false
@@ -88,6 +104,9 @@ func (fsc *FabricSDKClient) Init(configPath string) bool {
 	fsc.channelContextMap = make(map[string]context.Channel)
 	fsc.channelClientMap = make(map[string]*channel.Client)
 	fsc.eventSubsReg = make(map[string]EventWaitGroup)
+	fsc.eventHandlers = make(map[string]EventSink)
+	fsc.enrolledUsers = make(map[string]bool)
+	fsc.externalIdentities = make(map[string]*ExternalSigningIdentity)
 	configs, _ := fsc.configProvider()
 	ctxProvider := fsc.sdk.Context()
 	mspClient, err := mspclient.New(ctxProvider)
@@ -113,8 +132,40 @@ func (fsc *FabricSDKClient) Init(configPath string) bool {
 				break
 			}
 		}
+		if adminCert, loadAdminCert := cnfBackend.Lookup("x-remote-admin"); loadAdminCert {
+			_logger.Infof("Loading pre generated admin cert")
+			remoteAdmin, _ := adminCert.(string)
+			fsc.remoteAdminID = remoteAdmin
+			fsc.isRemoteAdmin = true
+			//The private key of the remote admin may be held by a signing service
+			if _, hasSigner := cnfBackend.Lookup("x-externalSigner"); hasSigner && !fsc.loadExternalSigner(cnfBackend) {
+				return false
+			}
+		}
+		if _, hasWallet := cnfBackend.Lookup("x-wallet"); hasWallet {
+			var walletConfig WalletConfig
+			if err := lookup.New(cnfBackend).UnmarshalKey("x-wallet", &walletConfig); err != nil {
+				_logger.Errorf("Invalid x-wallet configuration %+v", err)
+				return false
+			}
+			wallet, err := fsc.newWallet(walletConfig)
+			if err != nil {
+				_logger.Errorf("Unable to open the wallet %+v", err)
+				return false
+			}
+			fsc.wallet = wallet
+		}
+		if _, hasTLSEnrollment := cnfBackend.Lookup("x-tlsEnrollment"); hasTLSEnrollment {
+			var tlsEnrollment TLSEnrollment
+			if err := lookup.New(cnfBackend).UnmarshalKey("x-tlsEnrollment", &tlsEnrollment); err != nil {
+				_logger.Errorf("Invalid x-tlsEnrollment configuration %+v", err)
+				return false
+			}
+			fsc.tlsEnrollment = &tlsEnrollment
+		}
 		//To load a channel clients user and channel namesa are. If the x-preloadedUsers list is
 		//set in the configuration then they are loaded in init, else it is loaded.
+		//The wallet and the external signer are loaded first to sign for the users they hold.
 		if usersConf, loadUsers := cnfBackend.Lookup("x-preloadedUsers"); loadUsers {
 			users, _ := usersConf.([]interface{})
 
@@ -132,11 +183,27 @@ func (fsc *FabricSDKClient) Init(configPath string) bool {
 				}
 			}
 		}
-		if adminCert, loadAdminCert := cnfBackend.Lookup("x-remote-admin"); loadAdminCert {
-			_logger.Infof("Loading pre generated admin cert")
-			remoteAdmin, _ := adminCert.(string)
-			fsc.remoteAdminID = remoteAdmin
-			fsc.isRemoteAdmin = true
+		//Event sinks forwarding the registered events to files, webhooks or nats
+		if _, hasSinks := cnfBackend.Lookup("x-eventSinks"); hasSinks {
+			var checkpointConfig EventCheckpointConfig
+			var deadLetterConfig DeadLetterConfig
+			var sinkConfigs []EventSinkConfig
+			configLookup := lookup.New(cnfBackend)
+			if err := configLookup.UnmarshalKey("x-eventCheckpoint", &checkpointConfig); err != nil {
+				_logger.Errorf("Invalid x-eventCheckpoint configuration %+v", err)
+				return false
+			}
+			if err := configLookup.UnmarshalKey("x-deadLetter", &deadLetterConfig); err != nil {
+				_logger.Errorf("Invalid x-deadLetter configuration %+v", err)
+				return false
+			}
+			if err := configLookup.UnmarshalKey("x-eventSinks", &sinkConfigs); err != nil {
+				_logger.Errorf("Invalid x-eventSinks configuration %+v", err)
+				return false
+			}
+			if !fsc.loadEventSinks(checkpointConfig, deadLetterConfig, sinkConfigs) {
+				return false
+			}
 		}
 
 	}
@@ -148,26 +215,36 @@ func (fsc *FabricSDKClient) Init(configPath string) bool {
 func (fsc *FabricSDKClient) setupChannelClient(channelName, user string) (*channel.Client, bool) {
 	_logger.Debugf("Processing channel %s for user %s", channelName, user)
 	key := fmt.Sprintf("%s_%s", channelName, user)
-	fsc.channelContextProviderMap[key] = fsc.sdk.ChannelContext(channelName, fabsdk.WithUser(user), fabsdk.WithOrg(fsc.clientOrg))
-	channelContext, err := fsc.channelContextProviderMap[key]()
+	identityOption, err := fsc.identityOption(user)
+	if err != nil {
+		_logger.Errorf("Error in loading identity %+v", err)
+		return nil, false
+	}
+	channelContextProvider := fsc.getSDK().ChannelContext(channelName, identityOption, fabsdk.WithOrg(fsc.clientOrg))
+	channelContext, err := channelContextProvider()
 	if err != nil {
 		_logger.Errorf("Error in creating channel cotext %+v", err)
 		return nil, false
 	}
-	fsc.channelContextMap[key] = channelContext
-	channelClient, err := channel.New(fsc.channelContextProviderMap[key])
+	channelClient, err := channel.New(channelContextProvider)
 	if err != nil {
 		_logger.Errorf("Error in creating channel client %+v", err)
 		return nil, false
 	}
+	fsc.clientsLock.Lock()
+	fsc.channelContextProviderMap[key] = channelContextProvider
+	fsc.channelContextMap[key] = channelContext
 	fsc.channelClientMap[key] = channelClient
+	fsc.clientsLock.Unlock()
 	return channelClient, true
 }
 
 //getChannelClient returns an existing channel client. If not setup , setup is done internally
 func (fsc *FabricSDKClient) getChannelClient(channelName, user string) (*channel.Client, bool) {
 	key := fmt.Sprintf("%s_%s", channelName, user)
+	fsc.clientsLock.RLock()
 	client, isExisting := fsc.channelClientMap[key]
+	fsc.clientsLock.RUnlock()
 	if !isExisting {
 		_logger.Debugf("Not existing in the cnannel client map. Going to load %s", key)
 		return fsc.setupChannelClient(channelName, user)
@@ -175,12 +252,50 @@ func (fsc *FabricSDKClient) getChannelClient(channelName, user string) (*channel
 	return client, isExisting
 }
 
+//getChannelContext returns the channel context of the user, the channel client is set up first
+//if needed
+func (fsc *FabricSDKClient) getChannelContext(channelName, user string) (context.Channel, context.ChannelProvider, bool) {
+	if _, isFound := fsc.getChannelClient(channelName, user); !isFound {
+		return nil, nil, false
+	}
+	key := fmt.Sprintf("%s_%s", channelName, user)
+	fsc.clientsLock.RLock()
+	defer fsc.clientsLock.RUnlock()
+	channelContext, isFound := fsc.channelContextMap[key]
+	return channelContext, fsc.channelContextProviderMap[key], isFound
+}
+
+//getSDK returns the SDK, which EnrollTLS replaces
+func (fsc *FabricSDKClient) getSDK() *fabsdk.FabricSDK {
+	fsc.clientsLock.RLock()
+	defer fsc.clientsLock.RUnlock()
+	return fsc.sdk
+}
+
+//getConfigProvider returns the configuration of the SDK
+func (fsc *FabricSDKClient) getConfigProvider() core.ConfigProvider {
+	fsc.clientsLock.RLock()
+	defer fsc.clientsLock.RUnlock()
+	return fsc.configProvider
+}
+
+//getMSPClient returns the MSP client of the SDK
+func (fsc *FabricSDKClient) getMSPClient() *mspclient.Client {
+	fsc.clientsLock.RLock()
+	defer fsc.clientsLock.RUnlock()
+	return fsc.orgMSPClient
+}
+
 //Query method runs a query in the input channel. Returns the result , true/false and error object.
 //2nd bool return equals to true means no problem in executing the query.
 func (fsc *FabricSDKClient) Query(channelName, user, ccID, ccfuncName string, ccArgs [][]byte, targetPeers []string, wg *sync.WaitGroup) ([]byte, bool, error) {
 	if wg != nil {
 		defer wg.Done()
 	}
+	if err := fsc.beginWork(); err != nil {
+		return nil, false, err
+	}
+	defer fsc.endWork()
 	if channelClient, isFound := fsc.getChannelClient(channelName, user); isFound {
 		response, err := channelClient.Query(channel.Request{ChaincodeID: ccID, Fcn: ccfuncName, Args: ccArgs}, channel.WithTargetEndpoints(targetPeers...))
 		if err != nil {
@@ -199,6 +314,10 @@ func (fsc *FabricSDKClient) InvokeTrxn(channelName, user, ccID, ccfuncName strin
 	if wg != nil {
 		defer wg.Done()
 	}
+	if err := fsc.beginWork(); err != nil {
+		return nil, false, err
+	}
+	defer fsc.endWork()
 
 	if channelClient, isFound := fsc.getChannelClient(channelName, user); isFound {
 		response, err := channelClient.Execute(channel.Request{ChaincodeID: ccID, Fcn: ccfuncName, Args: ccArgs}, channel.WithTargetEndpoints(targetPeers...))
@@ -327,7 +446,7 @@ func (fsc *FabricSDKClient) SaveChannelInOrderer(channelID, pathToTxFile string,
 		_logger.Errorf("Failed to create new resource management client: %+v", err)
 		return false
 	}
-	mspClient, err := mspclient.New(fsc.sdk.Context(), mspclient.WithOrg(fsc.clientOrg))
+	mspClient, err := mspclient.New(fsc.getSDK().Context(), mspclient.WithOrg(fsc.clientOrg))
 	if err != nil {
 		_logger.Errorf("Error in creating  msp client for org %s %+v", fsc.clientOrg, err)
 		return false
@@ -379,15 +498,23 @@ func (fsc *FabricSDKClient) getAdminContext() context.ClientProvider {
 		adminID = fsc.remoteAdminID
 		_logger.Infof("Using remote admin %s", adminID)
 	}
-	_, err := fsc.orgMSPClient.GetSigningIdentity(adminID)
+	identityOption, err := fsc.identityOption(adminID)
 	if err != nil {
-		_logger.Fatalf("GetSigningIdentity failed: %s", err)
+		_logger.Fatalf("Admin identity failed: %s", err)
 		return nil
 	}
-	adminContext := fsc.sdk.Context(fabsdk.WithUser(adminID), fabsdk.WithOrg(fsc.clientOrg))
+	if fsc.Wallet() == nil && fsc.externalIdentity(adminID) == nil {
+		if _, err = fsc.getMSPClient().GetSigningIdentity(adminID); err != nil {
+			_logger.Fatalf("GetSigningIdentity failed: %s", err)
+			return nil
+		}
+	}
+	adminContext := fsc.getSDK().Context(identityOption, fabsdk.WithOrg(fsc.clientOrg))
 	return adminContext
 }
 func (fsc *FabricSDKClient) addEventInRegistry(eventDetails EventWaitGroup) bool {
+	fsc.eventSubsLock.Lock()
+	defer fsc.eventSubsLock.Unlock()
 	if _, isOk := fsc.eventSubsReg[eventDetails.eventName]; isOk {
 		_logger.Info("Event already registered %s", eventDetails.eventName)
 		return false
@@ -396,14 +523,64 @@ func (fsc *FabricSDKClient) addEventInRegistry(eventDetails EventWaitGroup) bool
 	return true
 }
 
-//RegisterForBlockEvents register for block events
+//removeEventFromRegistry removes and returns a registered event
+func (fsc *FabricSDKClient) removeEventFromRegistry(eventName string) (EventWaitGroup, bool) {
+	fsc.eventSubsLock.Lock()
+	defer fsc.eventSubsLock.Unlock()
+	eventDetails, isFound := fsc.eventSubsReg[eventName]
+	if isFound {
+		delete(fsc.eventSubsReg, eventName)
+	}
+	return eventDetails, isFound
+}
+
+//registerAdminBlockEvents registers for the block events of the channel with the org admin
+//context under eventName. The registration is removed by Shutdown or with removeEventFromRegistry.
+func (fsc *FabricSDKClient) registerAdminBlockEvents(channelID, eventName string, evtOptions ...options.Opt) (<-chan *fab.BlockEvent, bool) {
+	if err := fsc.beginWork(); err != nil {
+		_logger.Errorf("Client is shut down, %s is not registered", eventName)
+		return nil, false
+	}
+	defer fsc.endWork()
+	channelContext, _, isFound := fsc.getChannelContext(channelID, fsc.orgAdmin)
+	if !isFound {
+		return nil, false
+	}
+	eventService, err := channelContext.ChannelService().EventService(append([]options.Opt{eventClient.WithBlockEvents()}, evtOptions...)...)
+	if err != nil {
+		_logger.Errorf("Error getting event service: %+v", err)
+		return nil, false
+	}
+	evtRegistration, blockEventChan, err := eventService.RegisterBlockEvent()
+	if err != nil {
+		_logger.Errorf("Error registering for block events: %+v", err)
+		return nil, false
+	}
+	evntWg := EventWaitGroup{eventName: eventName, eventService: eventService, evtType: "BLOCK", registration: evtRegistration}
+	if !fsc.addEventInRegistry(evntWg) {
+		_logger.Errorf("Event already registered and running .. Unregister the other listener")
+		eventService.Unregister(evtRegistration)
+		return nil, false
+	}
+	return blockEventChan, true
+}
+
+//RegisterForBlockEvents register for block events. The events are also forwarded to the
+//event sinks configured for the channel, in that case eventLister may be nil.
 func (fsc *FabricSDKClient) RegisterForBlockEvents(channelID string, userID string, wg, wgListenr *sync.WaitGroup, eventLister BlockEventListener) bool {
 	if wg != nil {
 		defer wg.Done()
 	}
-	if _, isFound := fsc.getChannelClient(channelID, userID); isFound {
-		key := fmt.Sprintf("%s_%s", channelID, userID)
-		eventService, err := fsc.channelContextMap[key].ChannelService().EventService(eventClient.WithBlockEvents())
+	if err := fsc.beginWork(); err != nil {
+		_logger.Errorf("Client is shut down, block events of %s are not registered", channelID)
+		return false
+	}
+	defer fsc.endWork()
+	if channelContext, _, isFound := fsc.getChannelContext(channelID, userID); isFound {
+		eventName := fmt.Sprintf("%s_%s_BLOCKEVENT", channelID, userID)
+		sinks := fsc.sinksFor(SinkEventTypeBlock, channelID, "")
+		evtOptions := append([]options.Opt{eventClient.WithBlockEvents()}, fsc.eventServiceOptions(eventName, sinks)...)
+		eventService, err := channelContext.ChannelService().EventService(evtOptions...)
 		if err != nil {
 			_logger.Errorf("Error getting event service: %+v", err)
 			return false
@@ -414,15 +591,19 @@ func (fsc *FabricSDKClient) RegisterForBlockEvents(channelID string, userID stri
 			_logger.Errorf("Error registering for block events: %+v", err)
 			return false
 		}
-		eventName := fmt.Sprintf("%s_%s_BLOCKEVENT", channelID, userID)
-		evntWg := EventWaitGroup{eventName: eventName, eventService: eventService, evtType: "BLOCK", registration: evtRegistration, wg: wgListenr}
+		evntWg := EventWaitGroup{eventName: eventName, eventService: eventService, evtType: "BLOCK", registration: evtRegistration}
 		if !fsc.addEventInRegistry(evntWg) {
 			_logger.Errorf("Event already registered and running .. Unregister the other listener")
 			//Unregister right now
 			eventService.Unregister(evtRegistration)
 			return false
 		}
-		go eventLister(blockEventChan, wgListenr)
+		if len(sinks) > 0 {
+			blockEventChan = fsc.newEventForwarder(eventName, sinks).forwardBlockEvents(channelID, blockEventChan, eventLister != nil)
+		}
+		if eventLister != nil {
+			go eventLister(blockEventChan, wgListenr)
+		}
 		return true
 	}
 	return false
@@ -434,9 +615,13 @@ func (fsc *FabricSDKClient) RegisterForFilteredBlockEvents(channelID string, use
 	if wg != nil {
 		defer wg.Done()
 	}
-	if _, isFound := fsc.getChannelClient(channelID, userID); isFound {
-		key := fmt.Sprintf("%s_%s", channelID, userID)
-		eventService, err := fsc.channelContextMap[key].ChannelService().EventService(eventClient.WithBlockEvents())
+	if err := fsc.beginWork(); err != nil {
+		_logger.Errorf("Client is shut down, filtered block events of %s are not registered", channelID)
+		return false
+	}
+	defer fsc.endWork()
+	if channelContext, _, isFound := fsc.getChannelContext(channelID, userID); isFound {
+		eventService, err := channelContext.ChannelService().EventService(eventClient.WithBlockEvents())
 		if err != nil {
 			_logger.Errorf("Error getting event service: %+v", err)
 			return false
@@ -447,8 +632,8 @@ func (fsc *FabricSDKClient) RegisterForFilteredBlockEvents(channelID string, use
 			_logger.Errorf("Error registering for block events: %+v", err)
 			return false
 		}
-		eventName := fmt.Sprintf("%s_%s_BLOCKEVENT", channelID, userID)
-		evntWg := EventWaitGroup{eventName: eventName, eventService: eventService, evtType: "BLOCK", registration: evtRegistration, wg: wgListenr}
+		eventName := fmt.Sprintf("%s_%s_FILTEREDBLOCKEVENT", channelID, userID)
+		evntWg := EventWaitGroup{eventName: eventName, eventService: eventService, evtType: "FILTEREDBLOCK", registration: evtRegistration}
 		if !fsc.addEventInRegistry(evntWg) {
 			_logger.Errorf("Event already registered and running .. Unregister the other listener")
 			//Unregister right now
@@ -462,14 +647,51 @@ func (fsc *FabricSDKClient) RegisterForFilteredBlockEvents(channelID string, use
 
 }
 
-//RegisterForCCEvent register for chain code event
+//RegisterForCCEvent register for chain code event. The events are also forwarded to the
+//event sinks configured for the channel and chain code, in that case eventLister may be nil.
 func (fsc *FabricSDKClient) RegisterForCCEvent(channelID string, userID, ccID string, wg, wgListenr *sync.WaitGroup, eventLister CCEventListener) bool {
 	if wg != nil {
 		defer wg.Done()
 	}
-	if _, isFound := fsc.getChannelClient(channelID, userID); isFound {
-		key := fmt.Sprintf("%s_%s", channelID, userID)
-		eventService, err := fsc.channelContextMap[key].ChannelService().EventService(eventClient.WithBlockEvents())
+	return fsc.registerCCEvent(channelID, userID, ccID, wgListenr, eventLister, nil)
+}
+
+//RegisterCCEventHandler registers a handler called for each chain code event. Events for which
+//the handler fails are dead lettered. The handler shares the subscription with RegisterForCCEvent,
+//use DegisterCCevent to stop it.
+func (fsc *FabricSDKClient) RegisterCCEventHandler(channelID, userID, ccID string, handler CCEventHandler) bool {
+	if err := fsc.initEventStores(); err != nil {
+		_logger.Errorf("Error in creating event stores %+v", err)
+		return false
+	}
+	eventName := fmt.Sprintf("%s_%s_%s_CCEVENT", channelID, userID, ccID)
+	sink := &handlerSink{name: eventHandlerName(eventName), handler: handler}
+	if !fsc.registerCCEvent(channelID, userID, ccID, nil, nil, sink) {
+		return false
+	}
+	fsc.eventSinksLock.Lock()
+	defer fsc.eventSinksLock.Unlock()
+	if fsc.eventHandlers == nil {
+		fsc.eventHandlers = make(map[string]EventSink)
+	}
+	fsc.eventHandlers[sink.name] = sink
+	return true
+}
+
+func (fsc *FabricSDKClient) registerCCEvent(channelID, userID, ccID string, wgListenr *sync.WaitGroup, eventLister CCEventListener, handler EventSink) bool {
+	if err := fsc.beginWork(); err != nil {
+		_logger.Errorf("Client is shut down, chain code events of %s are not registered", ccID)
+		return false
+	}
+	defer fsc.endWork()
+	if channelContext, _, isFound := fsc.getChannelContext(channelID, userID); isFound {
+		eventName := fmt.Sprintf("%s_%s_%s_CCEVENT", channelID, userID, ccID)
+		sinks := fsc.sinksFor(SinkEventTypeCC, channelID, ccID)
+		if handler != nil {
+			sinks = append(sinks, handler)
+		}
+		evtOptions := append([]options.Opt{eventClient.WithBlockEvents()}, fsc.eventServiceOptions(eventName, sinks)...)
+		eventService, err := channelContext.ChannelService().EventService(evtOptions...)
 		if err != nil {
 			_logger.Errorf("Error getting event service: %+v", err)
 			return false
@@ -480,15 +702,19 @@ func (fsc *FabricSDKClient) RegisterForCCEvent(channelID string, userID, ccID st
 			_logger.Errorf("Error registering for block events: %+v", err)
 			return false
 		}
-		eventName := fmt.Sprintf("%s_%s_%s_CCEVENT", channelID, userID, ccID)
-		evntWg := EventWaitGroup{eventName: eventName, eventService: eventService, evtType: "CCEVENT", registration: evtRegistration, wg: wgListenr}
+		evntWg := EventWaitGroup{eventName: eventName, eventService: eventService, evtType: "CCEVENT", registration: evtRegistration}
 		if !fsc.addEventInRegistry(evntWg) {
 			_logger.Errorf("Event already registered and running .. Unregister the other listener")
 			//Unregister right now
 			eventService.Unregister(evtRegistration)
 			return false
 		}
-		go eventLister(ccEventChan, wgListenr)
+		if len(sinks) > 0 {
+			ccEventChan = fsc.newEventForwarder(eventName, sinks).forwardCCEvents(channelID, ccEventChan, eventLister != nil)
+		}
+		if eventLister != nil {
+			go eventLister(ccEventChan, wgListenr)
+		}
 		return true
 	}
 	return false
@@ -497,67 +723,56 @@ func (fsc *FabricSDKClient) RegisterForCCEvent(channelID string, userID, ccID st
 
 //DegisterBlockevent dergisters a block event from the channel
 func (fsc *FabricSDKClient) DegisterBlockevent(channelID, userID string) {
-	if evtWtGrp, isFound := fsc.eventSubsReg[fmt.Sprintf("%s_%s_BLOCKEVENT", channelID, userID)]; isFound {
+	if evtWtGrp, isFound := fsc.removeEventFromRegistry(fmt.Sprintf("%s_%s_BLOCKEVENT", channelID, userID)); isFound {
+		evtWtGrp.Deregister()
+	}
+}
+
+//DegisterFilteredBlockevent dergisters a filtered block event from the channel
+func (fsc *FabricSDKClient) DegisterFilteredBlockevent(channelID, userID string) {
+	if evtWtGrp, isFound := fsc.removeEventFromRegistry(fmt.Sprintf("%s_%s_FILTEREDBLOCKEVENT", channelID, userID)); isFound {
 		evtWtGrp.Deregister()
 	}
 }
 
 //DegisterCCevent deregister chain code event
 func (fsc *FabricSDKClient) DegisterCCevent(channelID, userID, ccID string) {
-	if evtWtGrp, isFound := fsc.eventSubsReg[fmt.Sprintf("%s_%s_%s_CCEVENT", channelID, userID, ccID)]; isFound {
+	eventName := fmt.Sprintf("%s_%s_%s_CCEVENT", channelID, userID, ccID)
+	if evtWtGrp, isFound := fsc.removeEventFromRegistry(eventName); isFound {
 		evtWtGrp.Deregister()
 	}
+	fsc.removeEventHandler(eventName)
 }
 
-//EnrollOrgUser reads the config and entrolls the registerer
+//EnrollOrgUser enrolls the user, registering it first as a user of the affiliation with
+//unlimited enrollments and the role1 and role2 ECert attributes when it is not registered yet.
+//Use RegisterAndEnrollUser to set other attributes or the identity type.
 func (fsc *FabricSDKClient) EnrollOrgUser(uid, secret, affiliationOrg string) bool {
 
 	//First try to retrive the user
-	err := fsc.orgMSPClient.Enroll(uid, mspclient.WithSecret(secret))
+	err := fsc.getMSPClient().Enroll(uid, mspclient.WithSecret(secret))
 	if err == nil {
 		_logger.Infof("User enrolled already : %s", uid)
+		fsc.addEnrolledUser(uid)
+		if err := fsc.storeInWallet(uid); err != nil {
+			_logger.Criticalf("%v", err)
+			return false
+		}
 		return true
 	}
-	//TODO: Study this following orgs once again
-	userAttributes := []mspclient.Attribute{
-		{
-			Name:  "role1",
-			Value: fmt.Sprintf("%s:ecert", "123"),
-			ECert: true,
-		},
-		{
-			Name:  "role2",
-			Value: fmt.Sprintf("%s:ecert", "123"),
-			ECert: true,
-		},
-	}
-	//If user enrollment error then
-	// Register the new user
-
-	_, err = fsc.orgMSPClient.Register(&mspclient.RegistrationRequest{
-		Name:           uid,
-		Type:           "user",
-		Attributes:     userAttributes,
-		Affiliation:    affiliationOrg,
-		MaxEnrollments: -1,
+	err = fsc.RegisterAndEnrollUser(&UserRegistration{
+		UserID:         uid,
 		Secret:         secret,
-	})
-	if err != nil {
-		_logger.Criticalf("Registration failed: %s", err)
-		return false
-	}
-
-	// Enroll the new user
-	err = fsc.orgMSPClient.Enroll(uid, mspclient.WithSecret(secret))
-	if err != nil {
-		_logger.Criticalf("Enroll failed: %s", err)
-		return false
-	}
-
-	// Get the new user's signing identity
-	_, err = fsc.orgMSPClient.GetSigningIdentity(uid)
+		Type:           IdentityTypeUser,
+		Affiliation:    affiliationOrg,
+		MaxEnrollments: UnlimitedEnrollments,
+		Attributes: []UserAttribute{
+			{Name: "role1", Value: "123:ecert", ECert: true},
+			{Name: "role2", Value: "123:ecert", ECert: true},
+		},
+	}, nil)
 	if err != nil {
-		_logger.Criticalf("GetSigningIdentity failed: %s", err)
+		_logger.Criticalf("%v", err)
 		return false
 	}
 	return true
@@ -569,7 +784,7 @@ func (fsc *FabricSDKClient) EnrollOrgUser(uid, secret, affiliationOrg string) bo
 func (fsc *FabricSDKClient) EnrollOrgAdmin(readFromConfig bool, adminUID string) bool {
 
 	if !readFromConfig {
-		_, err := fsc.orgMSPClient.GetSigningIdentity(adminUID)
+		_, err := fsc.userCertificate(adminUID)
 		if err != nil {
 			_logger.Criticalf("GetSigningIdentity failed: %s", err)
 			return false
@@ -579,7 +794,7 @@ func (fsc *FabricSDKClient) EnrollOrgAdmin(readFromConfig bool, adminUID string)
 		_logger.Info("Enrolled registerer ", fsc.orgAdmin)
 		return true
 	}
-	ctxProvider := fsc.sdk.Context()
+	ctxProvider := fsc.getSDK().Context()
 	ctx, err := ctxProvider()
 	if err != nil {
 		_logger.Criticalf("Failed to get context: %+v", err)
@@ -592,14 +807,24 @@ func (fsc *FabricSDKClient) EnrollOrgAdmin(readFromConfig bool, adminUID string)
 		return false
 	}
 
-	err = fsc.orgMSPClient.Enroll(caConfig.Registrar.EnrollID, mspclient.WithSecret(caConfig.Registrar.EnrollSecret))
+	err = fsc.getMSPClient().Enroll(caConfig.Registrar.EnrollID, mspclient.WithSecret(caConfig.Registrar.EnrollSecret))
 	if err != nil {
 		_logger.Criticalf("Registerer Enroll failed: %+v", err)
 		return false
 	}
 	fsc.orgAdmin = caConfig.Registrar.EnrollID
 	fsc.orgAdminSecret = caConfig.Registrar.EnrollSecret
+	if err := fsc.storeInWallet(fsc.orgAdmin); err != nil {
+		_logger.Criticalf("Registerer not stored in the wallet: %+v", err)
+		return false
+	}
 	_logger.Info("Enrolled registerer ", fsc.orgAdmin)
+	if fsc.tlsEnrollment != nil {
+		if err := fsc.EnrollTLS(*fsc.tlsEnrollment); err != nil {
+			_logger.Criticalf("Registerer TLS enrollment failed: %+v", err)
+			return false
+		}
+	}
 
 	return true
 
@@ -661,16 +886,30 @@ func (fsc *FabricSDKClient) GetChainCodeState(channel, ccID string) (bool, strin
 
 }
 
-//GetBlockdetails returns the details of a block
-func (fsc *FabricSDKClient) GetBlockdetails(channel string, blockNumber uint64) *commonpb.Block {
+//getLedgerClient returns a ledger client of the channel using the org admin channel context
+func (fsc *FabricSDKClient) getLedgerClient(channel string) (*ledger.Client, error) {
 	//To ensure that channel client is available
-	fsc.getChannelClient(channel, fsc.orgAdmin)
-	key := fmt.Sprintf("%s_%s", channel, fsc.orgAdmin)
-	channelContxt := fsc.channelContextProviderMap[key]
+	_, channelContextProvider, isFound := fsc.getChannelContext(channel, fsc.orgAdmin)
+	if !isFound {
+		return nil, fmt.Errorf("Channel cound not be found for %s channelname and user %s", channel, fsc.orgAdmin)
+	}
+	ledgerClient, err := ledger.New(channelContextProvider)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new ledger client for %s: %v", channel, err)
+	}
+	return ledgerClient, nil
+}
 
-	ledgerClient, err := ledger.New(channelContxt)
+//GetBlockdetails returns the details of a block
+func (fsc *FabricSDKClient) GetBlockdetails(channel string, blockNumber uint64) *commonpb.Block {
+	if err := fsc.beginWork(); err != nil {
+		_logger.Errorf("%+v", err)
+		return nil
+	}
+	defer fsc.endWork()
+	ledgerClient, err := fsc.getLedgerClient(channel)
 	if err != nil {
-		_logger.Errorf("Failed to create new resource management client: %s +%v", channel, err)
+		_logger.Errorf("%+v", err)
 		return nil
 	}
 	blockDetails, err := ledgerClient.QueryBlock(blockNumber)
@@ -681,12 +920,10 @@ func (fsc *FabricSDKClient) GetBlockdetails(channel string, blockNumber uint64)
 	return blockDetails
 }
 
-//Deregister  de-registers evnt wait group
+//Deregister  de-registers evnt wait group. The event channel is closed, the listener returning
+//on the closed channel marks its wait group done.
 func (ewg *EventWaitGroup) Deregister() {
 	ewg.eventService.Unregister(ewg.registration)
-	if ewg.wg != nil {
-		ewg.wg.Done()
-	}
 }
 
 /*
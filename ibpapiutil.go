@@ -126,18 +126,11 @@ func (ibpc *IBPClient) getAuthHeaderValue() string {
 	return authHeaderValue
 }
 
-//GeneratyeCertKeyEntry prints the cert key value entry
+//GenerateCertKeyEntry prints the cert key value entry
 func (ibpc *IBPClient) GenerateCertKeyEntry(certPath, privKeyPath string) {
 	certBytes, _ := ioutil.ReadFile(certPath)
 	keyBytes, _ := ioutil.ReadFile(privKeyPath)
-	output := make(map[string]interface{})
-	pemCert := make(map[string]string)
-	pemCert["pem"] = string(certBytes)
-	output["cert"] = pemCert
-	pemKey := make(map[string]interface{})
-	pemKey["pem"] = string(keyBytes)
-	output["key"] = pemKey
-	finalOutput, _ := json.MarshalIndent(output, "", " ")
+	finalOutput, _ := IBPCertKeyJSON(certBytes, keyBytes)
 	fmt.Println(string(finalOutput))
 }
 
@@ -0,0 +1,263 @@
+package fabricgosdkclientcore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	core "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/core"
+	cryptosuite "github.com/hyperledger/fabric-sdk-go/pkg/core/cryptosuite"
+	fabsdk "github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+)
+
+//StoredIdentity is the certificate and private key of an identity, both PEM encoded
+type StoredIdentity struct {
+	UserID  string `json:"userId"`
+	MSPID   string `json:"mspId"`
+	CertPEM []byte `json:"cert"`
+	KeyPEM  []byte `json:"key"`
+	//CACertsPEM are the certificates of the CA chain, used for the MSP directory
+	CACertsPEM []byte `json:"caCerts,omitempty"`
+}
+
+//parsePrivateKeyPEM parses an ECDSA private key in PKCS#8 or SEC 1 form
+func parsePrivateKeyPEM(keyPEM []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM encoded private key found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		ecKey, isEC := key.(*ecdsa.PrivateKey)
+		if !isEC {
+			return nil, fmt.Errorf("Private key is not an ECDSA key")
+		}
+		return ecKey, nil
+	}
+	ecKey, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid private key: %v", err)
+	}
+	return ecKey, nil
+}
+
+//publicKeySKI is the subject key identifier the SDK key store names the private keys with
+func publicKeySKI(publicKey *ecdsa.PublicKey) string {
+	hash := sha256.Sum256(elliptic.Marshal(publicKey.Curve, publicKey.X, publicKey.Y))
+	return hex.EncodeToString(hash[:])
+}
+
+//ValidateCertKeyPair checks the private key belongs to the certificate and returns the
+//subject key identifier of the key
+func ValidateCertKeyPair(certPEM, keyPEM []byte) (string, error) {
+	certificate, err := parsePEMCertificate(certPEM)
+	if err != nil {
+		return "", err
+	}
+	certKey, isEC := certificate.PublicKey.(*ecdsa.PublicKey)
+	if !isEC {
+		return "", fmt.Errorf("Certificate %s has no ECDSA public key", certificate.Subject.CommonName)
+	}
+	privateKey, err := parsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return "", err
+	}
+	if certKey.Curve != privateKey.Curve || certKey.X.Cmp(privateKey.X) != 0 || certKey.Y.Cmp(privateKey.Y) != 0 {
+		return "", fmt.Errorf("Private key does not match the certificate of %s", certificate.Subject.CommonName)
+	}
+	return publicKeySKI(certKey), nil
+}
+
+//identityCertFileName is the name of the certificate of a user in the credential store
+func identityCertFileName(userID, mspID string) string {
+	return fmt.Sprintf("%s@%s-cert.pem", userID, mspID)
+}
+
+//WriteIdentityFiles writes the certificate in the credential store and the private key in the
+//key store with the names the SDK looks them up with
+func WriteIdentityFiles(credentialStorePath, keyStorePath string, identity *StoredIdentity) error {
+	ski, err := ValidateCertKeyPair(identity.CertPEM, identity.KeyPEM)
+	if err != nil {
+		return err
+	}
+	privateKey, _ := parsePrivateKeyPEM(identity.KeyPEM)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("Unable to encode the private key of %s: %v", identity.UserID, err)
+	}
+	keyPath := filepath.Join(keyStorePath, ski+"_sk")
+	if err := writeFileAtomicMode(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return fmt.Errorf("Unable to write %s: %v", keyPath, err)
+	}
+	certPath := filepath.Join(credentialStorePath, identityCertFileName(identity.UserID, identity.MSPID))
+	if err := writeFileAtomic(certPath, identity.CertPEM); err != nil {
+		return fmt.Errorf("Unable to write %s: %v", certPath, err)
+	}
+	return nil
+}
+
+//ReadIdentityFiles reads the certificate of a user from the credential store and its private key
+//from the key store
+func ReadIdentityFiles(credentialStorePath, keyStorePath, userID, mspID string) (*StoredIdentity, error) {
+	certPath := filepath.Join(credentialStorePath, identityCertFileName(userID, mspID))
+	certPEM, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the certificate of %s: %v", userID, err)
+	}
+	certificate, err := parsePEMCertificate(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("Certificate of %s: %v", userID, err)
+	}
+	certKey, isEC := certificate.PublicKey.(*ecdsa.PublicKey)
+	if !isEC {
+		return nil, fmt.Errorf("Certificate of %s has no ECDSA public key", userID)
+	}
+	keyPEM, err := ioutil.ReadFile(filepath.Join(keyStorePath, publicKeySKI(certKey)+"_sk"))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the private key of %s: %v", userID, err)
+	}
+	return &StoredIdentity{UserID: userID, MSPID: mspID, CertPEM: certPEM, KeyPEM: keyPEM}, nil
+}
+
+//IBPCertKeyJSON formats a certificate and a private key the way the IBP console imports them
+func IBPCertKeyJSON(certPEM, keyPEM []byte) ([]byte, error) {
+	output := map[string]map[string]string{
+		"cert": {"pem": string(certPEM)},
+		"key":  {"pem": string(keyPEM)},
+	}
+	return json.MarshalIndent(output, "", " ")
+}
+
+//IBPJSON formats the identity the way the IBP console imports it
+func (si *StoredIdentity) IBPJSON() ([]byte, error) {
+	return IBPCertKeyJSON(si.CertPEM, si.KeyPEM)
+}
+
+//WriteMSPDir writes the identity as a Fabric MSP directory: signcerts, keystore, cacerts when the
+//CA chain is known and admincerts
+func (si *StoredIdentity) WriteMSPDir(dir string) error {
+	ski, err := ValidateCertKeyPair(si.CertPEM, si.KeyPEM)
+	if err != nil {
+		return err
+	}
+	keyPath := filepath.Join(dir, "keystore", ski+"_sk")
+	if err := writeFileAtomicMode(keyPath, si.KeyPEM, 0600); err != nil {
+		return fmt.Errorf("Unable to write %s: %v", keyPath, err)
+	}
+	certFiles := map[string][]byte{
+		filepath.Join(dir, "signcerts", "cert.pem"):  si.CertPEM,
+		filepath.Join(dir, "admincerts", "cert.pem"): si.CertPEM,
+	}
+	if len(si.CACertsPEM) > 0 {
+		certFiles[filepath.Join(dir, "cacerts", "ca.pem")] = si.CACertsPEM
+	}
+	for certPath, certPEM := range certFiles {
+		if err := writeFileAtomic(certPath, certPEM); err != nil {
+			return fmt.Errorf("Unable to write %s: %v", certPath, err)
+		}
+	}
+	return nil
+}
+
+//identityStorePaths returns the credential store and key store paths the way the SDK resolves
+//them from the configuration
+func (fsc *FabricSDKClient) identityStorePaths() (string, string, error) {
+	fsc.clientsLock.RLock()
+	sdk, configProvider := fsc.sdk, fsc.configProvider
+	fsc.clientsLock.RUnlock()
+	return sdkStorePaths(sdk, configProvider)
+}
+
+//sdkStorePaths returns the identity store paths of an SDK created from the configuration provider
+func sdkStorePaths(sdk *fabsdk.FabricSDK, configProvider core.ConfigProvider) (string, string, error) {
+	ctx, err := sdk.Context()()
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to get context: %v", err)
+	}
+	configs, err := configProvider()
+	if err != nil {
+		return "", "", fmt.Errorf("Unable to read the configuration: %v", err)
+	}
+	credentialStorePath := ctx.IdentityConfig().CredentialStorePath()
+	keyStorePath := cryptosuite.ConfigFromBackend(configs...).KeyStorePath()
+	if len(credentialStorePath) == 0 || len(keyStorePath) == 0 {
+		return "", "", fmt.Errorf("client.credentialStore.path and client.credentialStore.cryptoStore.path are required")
+	}
+	return credentialStorePath, keyStorePath, nil
+}
+
+//ImportIdentity stores a pre generated certificate and private key so that the user can be used
+//like an enrolled one, for example as x-remote-admin. With a wallet other than the file wallet
+//the identity goes to the wallet, it is written to the SDK stores otherwise.
+func (fsc *FabricSDKClient) ImportIdentity(userID, mspID string, certPEM, keyPEM []byte) error {
+	identity := &StoredIdentity{UserID: userID, MSPID: mspID, CertPEM: certPEM, KeyPEM: keyPEM}
+	wallet := fsc.Wallet()
+	if _, isFileWallet := wallet.(*FileWallet); wallet != nil && !isFileWallet {
+		if _, err := ValidateCertKeyPair(certPEM, keyPEM); err != nil {
+			return err
+		}
+		if err := wallet.Put(identity); err != nil {
+			return fmt.Errorf("Unable to store %s in the wallet: %v", userID, err)
+		}
+		if _, err := fsc.walletIdentity(userID); err != nil {
+			return fmt.Errorf("Imported identity %s is not usable: %v", userID, err)
+		}
+		_logger.Infof("Identity %s of %s imported in the wallet", userID, mspID)
+		return nil
+	}
+	credentialStorePath, keyStorePath, err := fsc.identityStorePaths()
+	if err != nil {
+		return err
+	}
+	if err := WriteIdentityFiles(credentialStorePath, keyStorePath, identity); err != nil {
+		return err
+	}
+	if _, err := fsc.getMSPClient().GetSigningIdentity(userID); err != nil {
+		return fmt.Errorf("Imported identity %s is not usable: %v", userID, err)
+	}
+	_logger.Infof("Identity %s of %s imported", userID, mspID)
+	return nil
+}
+
+//ExportIdentity returns the certificate and private key of a user of the wallet or of the
+//credential store with the CA chain of the organization when the CA is reachable
+func (fsc *FabricSDKClient) ExportIdentity(userID string) (*StoredIdentity, error) {
+	var identity *StoredIdentity
+	var err error
+	if wallet := fsc.Wallet(); wallet != nil {
+		identity, err = wallet.Get(userID)
+		if err != nil && err != ErrIdentityNotFound {
+			return nil, err
+		}
+	}
+	if identity == nil {
+		if identity, err = fsc.readStoreIdentity(userID); err != nil {
+			return nil, err
+		}
+	}
+	if caInfo, err := fsc.getMSPClient().GetCAInfo(); err != nil {
+		_logger.Warningf("CA chain of %s not exported: %v", userID, err)
+	} else {
+		identity.CACertsPEM = caInfo.CAChain
+	}
+	return identity, nil
+}
+
+//readStoreIdentity reads the certificate and private key of a user of the credential store
+func (fsc *FabricSDKClient) readStoreIdentity(userID string) (*StoredIdentity, error) {
+	credentialStorePath, keyStorePath, err := fsc.identityStorePaths()
+	if err != nil {
+		return nil, err
+	}
+	signingIdentity, err := fsc.getMSPClient().GetSigningIdentity(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the signing identity of %s: %v", userID, err)
+	}
+	return ReadIdentityFiles(credentialStorePath, keyStorePath, userID, signingIdentity.Identifier().MSPID)
+}
@@ -0,0 +1,165 @@
+package fabricgosdkclientcore
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//DefaultInvalidTxExamples is the number of example transaction IDs kept per group
+const DefaultInvalidTxExamples = 5
+
+//InvalidTxGroup counts the invalid transactions with the same validation code, chain code,
+//function and creator
+type InvalidTxGroup struct {
+	ValidationCodeName string   `json:"validationCodeName"`
+	ChaincodeName      string   `json:"chaincodeName"`
+	Function           string   `json:"function"`
+	Creator            string   `json:"creator"`
+	Count              int      `json:"count"`
+	FirstBlock         uint64   `json:"firstBlock"`
+	LastBlock          uint64   `json:"lastBlock"`
+	ExampleTxIDs       []string `json:"exampleTxIds"`
+}
+
+//InvalidTxReport is the summary of the invalid transactions of a block range
+type InvalidTxReport struct {
+	Channel     string            `json:"channel"`
+	FromBlock   uint64            `json:"fromBlock"`
+	ToBlock     uint64            `json:"toBlock"`
+	TotalTxs    int               `json:"totalTxs"`
+	InvalidTxs  int               `json:"invalidTxs"`
+	ByCode      map[string]int    `json:"byCode"`
+	ByChaincode map[string]int    `json:"byChaincode"`
+	ByFunction  map[string]int    `json:"byFunction"`
+	ByCreator   map[string]int    `json:"byCreator"`
+	Groups      []*InvalidTxGroup `json:"groups"`
+	maxExamples int
+	groupIndex  map[string]*InvalidTxGroup
+}
+
+//NewInvalidTxReport creates an empty report keeping up to maxExamples transaction IDs per group
+func NewInvalidTxReport(channel string, maxExamples int) *InvalidTxReport {
+	if maxExamples <= 0 {
+		maxExamples = DefaultInvalidTxExamples
+	}
+	return &InvalidTxReport{
+		Channel:     channel,
+		ByCode:      make(map[string]int),
+		ByChaincode: make(map[string]int),
+		ByFunction:  make(map[string]int),
+		ByCreator:   make(map[string]int),
+		Groups:      make([]*InvalidTxGroup, 0),
+		maxExamples: maxExamples,
+		groupIndex:  make(map[string]*InvalidTxGroup),
+	}
+}
+
+//Add counts the transactions of a block
+func (itr *InvalidTxReport) Add(block *DecodedBlock) {
+	if itr.TotalTxs == 0 && itr.InvalidTxs == 0 {
+		itr.FromBlock = block.Number
+	}
+	itr.ToBlock = block.Number
+	for _, transaction := range block.Transactions {
+		itr.TotalTxs++
+		if transaction.IsValid() {
+			continue
+		}
+		itr.InvalidTxs++
+		creator := transaction.CreatorMSPID
+		if len(transaction.CreatorSubject) > 0 {
+			creator = creator + " " + transaction.CreatorSubject
+		}
+		itr.ByCode[transaction.ValidationCodeName]++
+		itr.ByChaincode[transaction.ChaincodeName]++
+		itr.ByFunction[transaction.ChaincodeName+"."+transaction.Function]++
+		itr.ByCreator[creator]++
+		groupKey := strings.Join([]string{transaction.ValidationCodeName, transaction.ChaincodeName, transaction.Function, creator}, "\x00")
+		group, isFound := itr.groupIndex[groupKey]
+		if !isFound {
+			group = &InvalidTxGroup{
+				ValidationCodeName: transaction.ValidationCodeName,
+				ChaincodeName:      transaction.ChaincodeName,
+				Function:           transaction.Function,
+				Creator:            creator,
+				FirstBlock:         block.Number,
+				ExampleTxIDs:       make([]string, 0, itr.maxExamples),
+			}
+			itr.groupIndex[groupKey] = group
+			itr.Groups = append(itr.Groups, group)
+		}
+		group.Count++
+		group.LastBlock = block.Number
+		if len(group.ExampleTxIDs) < itr.maxExamples {
+			group.ExampleTxIDs = append(group.ExampleTxIDs, transaction.TxID)
+		}
+	}
+	//Most frequent failures first
+	sort.SliceStable(itr.Groups, func(i, j int) bool {
+		return itr.Groups[i].Count > itr.Groups[j].Count
+	})
+}
+
+//WriteJSON writes the report as indented JSON
+func (itr *InvalidTxReport) WriteJSON(writer io.Writer) error {
+	content, err := json.MarshalIndent(itr, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(content)
+	return err
+}
+
+//WriteCSV writes one line per group with a header line. The example transaction IDs are
+//separated by spaces.
+func (itr *InvalidTxReport) WriteCSV(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Write([]string{"validation_code", "chaincode", "function", "creator", "count", "first_block", "last_block", "example_tx_ids"})
+	for _, group := range itr.Groups {
+		csvWriter.Write([]string{
+			group.ValidationCodeName,
+			group.ChaincodeName,
+			group.Function,
+			group.Creator,
+			strconv.Itoa(group.Count),
+			strconv.FormatUint(group.FirstBlock, 10),
+			strconv.FormatUint(group.LastBlock, 10),
+			strings.Join(group.ExampleTxIDs, " "),
+		})
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+//GetInvalidTxReport scans the blocks from block number from to block number to of the channel
+//ledger and reports the invalid transactions
+func (fsc *FabricSDKClient) GetInvalidTxReport(channel string, from, to uint64, maxExamples int) (*InvalidTxReport, error) {
+	if from > to {
+		return nil, fmt.Errorf("Blocks are scanned in increasing order, %d is after %d", from, to)
+	}
+	iterator, err := fsc.NewLedgerBlockIterator(channel, from, to, DefaultBlockFetchConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	report := NewInvalidTxReport(channel, maxExamples)
+	for {
+		block, err := iterator.Next()
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		decodedBlock, err := DecodeBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		report.Add(decodedBlock)
+	}
+}
@@ -0,0 +1,142 @@
+package fabricgosdkclientcore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+//KeyModification is a value written or a delete of a key by a valid transaction
+type KeyModification struct {
+	TxID           string    `json:"txId"`
+	BlockNumber    uint64    `json:"blockNumber"`
+	TxIndex        int       `json:"txIndex"`
+	Timestamp      time.Time `json:"timestamp"`
+	CreatorMSPID   string    `json:"creatorMspId"`
+	CreatorSubject string    `json:"creatorSubject,omitempty"`
+	Value          []byte    `json:"value,omitempty"`
+	IsDelete       bool      `json:"isDelete"`
+}
+
+//KeyHistory collects the modifications of a key of a chain code namespace from decoded blocks
+//given in ledger order. Only the write sets of valid transactions are counted, the writes of
+//invalid transactions were never applied to the world state.
+type KeyHistory struct {
+	Namespace     string             `json:"namespace"`
+	Key           string             `json:"key"`
+	Modifications []*KeyModification `json:"modifications"`
+}
+
+//NewKeyHistory creates an empty history of the key
+func NewKeyHistory(namespace, key string) *KeyHistory {
+	return &KeyHistory{Namespace: namespace, Key: key, Modifications: make([]*KeyModification, 0)}
+}
+
+//Add adds the modifications of the key in the block
+func (kh *KeyHistory) Add(block *DecodedBlock) {
+	for _, transaction := range block.Transactions {
+		if !transaction.IsValid() {
+			continue
+		}
+		for _, rwSet := range transaction.RWSets {
+			if rwSet.Namespace != kh.Namespace {
+				continue
+			}
+			for _, write := range rwSet.Writes {
+				if write.Key != kh.Key {
+					continue
+				}
+				kh.Modifications = append(kh.Modifications, &KeyModification{
+					TxID:           transaction.TxID,
+					BlockNumber:    block.Number,
+					TxIndex:        transaction.Index,
+					Timestamp:      transaction.Timestamp,
+					CreatorMSPID:   transaction.CreatorMSPID,
+					CreatorSubject: transaction.CreatorSubject,
+					Value:          write.Value,
+					IsDelete:       write.IsDelete,
+				})
+			}
+		}
+	}
+}
+
+//Current returns the last modification, nil if the key was never written
+func (kh *KeyHistory) Current() *KeyModification {
+	if len(kh.Modifications) == 0 {
+		return nil
+	}
+	return kh.Modifications[len(kh.Modifications)-1]
+}
+
+//GetKeyHistory reconstructs the history of a key from the blocks from block number from to block
+//number to of the channel ledger
+func (fsc *FabricSDKClient) GetKeyHistory(channel, namespace, key string, from, to uint64) (*KeyHistory, error) {
+	if from > to {
+		return nil, fmt.Errorf("Key history is built in ledger order, %d is after %d", from, to)
+	}
+	iterator, err := fsc.NewLedgerBlockIterator(channel, from, to, DefaultBlockFetchConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	history := NewKeyHistory(namespace, key)
+	for {
+		block, err := iterator.Next()
+		if err == io.EOF {
+			return history, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		decodedBlock, err := DecodeBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		history.Add(decodedBlock)
+	}
+}
+
+//KeyHistory returns the history of a key from the write sets stored in the index
+func (bi *BlockIndexer) KeyHistory(namespace, key string) (*KeyHistory, error) {
+	history := NewKeyHistory(namespace, key)
+	prefix := indexPrefix(namespace, key)
+	err := bi.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(indexBucketKeys).Cursor()
+		for indexKey, writeBytes := cursor.Seek(prefix); indexKey != nil && bytes.HasPrefix(indexKey, prefix); indexKey, writeBytes = cursor.Next() {
+			if !isExactIndexKey(indexKey, prefix) {
+				continue
+			}
+			indexed, err := getIndexedTx(tx, indexKey[len(indexKey)-txHeightLength:])
+			if err != nil {
+				return err
+			}
+			if !indexed.IsValid() {
+				continue
+			}
+			write := new(indexedWrite)
+			if err := json.Unmarshal(writeBytes, write); err != nil {
+				return fmt.Errorf("Invalid indexed write of %s: %v", key, err)
+			}
+			history.Modifications = append(history.Modifications, &KeyModification{
+				TxID:           indexed.TxID,
+				BlockNumber:    indexed.BlockNumber,
+				TxIndex:        indexed.TxIndex,
+				Timestamp:      indexed.Timestamp,
+				CreatorMSPID:   indexed.CreatorMSPID,
+				CreatorSubject: indexed.CreatorSubject,
+				Value:          write.Value,
+				IsDelete:       write.IsDelete,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
@@ -0,0 +1,263 @@
+package fabricgosdkclientcore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	ledger "github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+)
+
+//PeerLedgerInfo is the ledger info reported by one peer
+type PeerLedgerInfo struct {
+	Peer             string `json:"peer"`
+	Org              string `json:"org"`
+	Height           uint64 `json:"height"`
+	CurrentBlockHash string `json:"currentBlockHash"`
+	Lagging          bool   `json:"lagging"`
+	Diverging        bool   `json:"diverging"`
+	Error            string `json:"error,omitempty"`
+}
+
+//LedgerComparison is the ledger info of every peer of a channel. A peer is lagging when its
+//height is below the highest one and diverging when its current block differs from the block
+//at the same height on the other peers.
+type LedgerComparison struct {
+	Channel   string            `json:"channel"`
+	MaxHeight uint64            `json:"maxHeight"`
+	InSync    bool              `json:"inSync"`
+	Peers     []*PeerLedgerInfo `json:"peers"`
+}
+
+//BlockDiff is the comparison of the same block fetched from two peers
+type BlockDiff struct {
+	BlockNumber uint64   `json:"blockNumber"`
+	PeerA       string   `json:"peerA"`
+	PeerB       string   `json:"peerB"`
+	HashA       string   `json:"hashA"`
+	HashB       string   `json:"hashB"`
+	Identical   bool     `json:"identical"`
+	Differences []string `json:"differences,omitempty"`
+}
+
+//CompareLedgers queries the ledger info of the channel from every peer of every organization in
+//the configuration and flags the peers lagging behind or diverging from the others
+func (fsc *FabricSDKClient) CompareLedgers(channel string) (*LedgerComparison, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	ledgerClient, err := fsc.getLedgerClient(channel)
+	if err != nil {
+		return nil, err
+	}
+	orgPeers, err := fsc.channelPeersByOrg(channel)
+	if err != nil {
+		return nil, err
+	}
+	comparison := &LedgerComparison{Channel: channel, InSync: true, Peers: make([]*PeerLedgerInfo, 0)}
+	for _, org := range sortedKeys(orgPeers) {
+		for _, peer := range orgPeers[org] {
+			peerInfo := &PeerLedgerInfo{Peer: peer, Org: org}
+			comparison.Peers = append(comparison.Peers, peerInfo)
+			info, err := ledgerClient.QueryInfo(ledger.WithTargetEndpoints(peer))
+			if err != nil {
+				peerInfo.Error = err.Error()
+				comparison.InSync = false
+				continue
+			}
+			peerInfo.Height = info.BCI.GetHeight()
+			peerInfo.CurrentBlockHash = hex.EncodeToString(info.BCI.GetCurrentBlockHash())
+			if peerInfo.Height > comparison.MaxHeight {
+				comparison.MaxHeight = peerInfo.Height
+			}
+		}
+	}
+	//The hash reported by most peers at a height is taken as the reference block of that height
+	hashCounts := make(map[uint64]map[string]int)
+	for _, peerInfo := range comparison.Peers {
+		if len(peerInfo.Error) == 0 {
+			if hashCounts[peerInfo.Height] == nil {
+				hashCounts[peerInfo.Height] = make(map[string]int)
+			}
+			hashCounts[peerInfo.Height][peerInfo.CurrentBlockHash]++
+		}
+	}
+	var referencePeer string
+	for _, peerInfo := range comparison.Peers {
+		if len(peerInfo.Error) > 0 {
+			continue
+		}
+		peerInfo.Diverging = !isMajorityHash(hashCounts[peerInfo.Height], peerInfo.CurrentBlockHash)
+		if peerInfo.Height == comparison.MaxHeight && !peerInfo.Diverging && len(referencePeer) == 0 {
+			referencePeer = peerInfo.Peer
+		}
+	}
+	for _, peerInfo := range comparison.Peers {
+		if len(peerInfo.Error) > 0 {
+			continue
+		}
+		peerInfo.Lagging = peerInfo.Height < comparison.MaxHeight
+		//A lagging peer also diverges when its current block is not the block of that number on the reference peer
+		if peerInfo.Lagging && !peerInfo.Diverging && peerInfo.Height > 0 && len(referencePeer) > 0 {
+			block, err := ledgerClient.QueryBlock(peerInfo.Height-1, ledger.WithTargetEndpoints(referencePeer))
+			if err != nil {
+				peerInfo.Error = fmt.Sprintf("Unable to fetch block %d from %s: %v", peerInfo.Height-1, referencePeer, err)
+			} else {
+				peerInfo.Diverging = hex.EncodeToString(BlockHeaderHash(block.Header)) != peerInfo.CurrentBlockHash
+			}
+		}
+		if peerInfo.Lagging || peerInfo.Diverging || len(peerInfo.Error) > 0 {
+			comparison.InSync = false
+		}
+	}
+	return comparison, nil
+}
+
+func isMajorityHash(hashCounts map[string]int, hash string) bool {
+	for otherHash, count := range hashCounts {
+		if otherHash != hash && count >= hashCounts[hash] {
+			return false
+		}
+	}
+	return true
+}
+
+//channelPeersByOrg returns the peers of each organization in the configuration. When the channel
+//lists its peers, the peers not joined to the channel are left out.
+func (fsc *FabricSDKClient) channelPeersByOrg(channel string) (map[string][]string, error) {
+	configs, err := fsc.getConfigProvider()()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the configuration: %v", err)
+	}
+	orgPeers := make(map[string][]string)
+	var channelPeers map[string]interface{}
+	for _, cnfBackend := range configs {
+		if channelPeersConfig, isFound := cnfBackend.Lookup(fmt.Sprintf("channels.%s.peers", channel)); isFound {
+			channelPeers, _ = channelPeersConfig.(map[string]interface{})
+		}
+		orgsConfig, isFound := cnfBackend.Lookup("organizations")
+		if !isFound {
+			continue
+		}
+		orgsConfigMap, _ := orgsConfig.(map[string]interface{})
+		for org, orgConfig := range orgsConfigMap {
+			orgConfigMap, _ := orgConfig.(map[string]interface{})
+			peers, _ := orgConfigMap["peers"].([]interface{})
+			for _, peer := range peers {
+				if peerName, isOk := peer.(string); isOk {
+					orgPeers[org] = append(orgPeers[org], peerName)
+				}
+			}
+		}
+	}
+	if len(channelPeers) > 0 {
+		for org, peers := range orgPeers {
+			joinedPeers := make([]string, 0, len(peers))
+			for _, peer := range peers {
+				if _, isJoined := channelPeers[strings.ToLower(peer)]; isJoined {
+					joinedPeers = append(joinedPeers, peer)
+				}
+			}
+			orgPeers[org] = joinedPeers
+		}
+	}
+	if len(orgPeers) == 0 {
+		return nil, fmt.Errorf("No peers configured for channel %s", channel)
+	}
+	return orgPeers, nil
+}
+
+func sortedKeys(orgPeers map[string][]string) []string {
+	keys := make([]string, 0, len(orgPeers))
+	for key := range orgPeers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//DiffBlockAcrossPeers fetches a block from two peers and lists the differences
+func (fsc *FabricSDKClient) DiffBlockAcrossPeers(channel string, blockNumber uint64, peerA, peerB string) (*BlockDiff, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	ledgerClient, err := fsc.getLedgerClient(channel)
+	if err != nil {
+		return nil, err
+	}
+	blockA, err := ledgerClient.QueryBlock(blockNumber, ledger.WithTargetEndpoints(peerA))
+	if err != nil {
+		return nil, fmt.Errorf("Error in retriving the block %d from %s: %v", blockNumber, peerA, err)
+	}
+	blockB, err := ledgerClient.QueryBlock(blockNumber, ledger.WithTargetEndpoints(peerB))
+	if err != nil {
+		return nil, fmt.Errorf("Error in retriving the block %d from %s: %v", blockNumber, peerB, err)
+	}
+	diff := DiffBlocks(blockA, blockB)
+	diff.PeerA = peerA
+	diff.PeerB = peerB
+	return diff, nil
+}
+
+//DiffBlocks lists the differences between two copies of a block: header fields, transactions and
+//block metadata. The metadata is written by each peer, the validation codes may differ between
+//peers with identical block data.
+func DiffBlocks(blockA, blockB *commonpb.Block) *BlockDiff {
+	diff := &BlockDiff{
+		BlockNumber: blockA.GetHeader().GetNumber(),
+		HashA:       hex.EncodeToString(BlockHeaderHash(blockA.GetHeader())),
+		HashB:       hex.EncodeToString(BlockHeaderHash(blockB.GetHeader())),
+		Differences: make([]string, 0),
+	}
+	addDiff := func(format string, args ...interface{}) {
+		diff.Differences = append(diff.Differences, fmt.Sprintf(format, args...))
+	}
+	headerA, headerB := blockA.GetHeader(), blockB.GetHeader()
+	if headerA.GetNumber() != headerB.GetNumber() {
+		addDiff("number %d != %d", headerA.GetNumber(), headerB.GetNumber())
+	}
+	if !bytes.Equal(headerA.GetPreviousHash(), headerB.GetPreviousHash()) {
+		addDiff("previous hash %x != %x", headerA.GetPreviousHash(), headerB.GetPreviousHash())
+	}
+	if !bytes.Equal(headerA.GetDataHash(), headerB.GetDataHash()) {
+		addDiff("data hash %x != %x", headerA.GetDataHash(), headerB.GetDataHash())
+	}
+	dataA, dataB := blockA.GetData().GetData(), blockB.GetData().GetData()
+	if len(dataA) != len(dataB) {
+		addDiff("transaction count %d != %d", len(dataA), len(dataB))
+	}
+	for index := 0; index < len(dataA) && index < len(dataB); index++ {
+		if !bytes.Equal(dataA[index], dataB[index]) {
+			addDiff("transaction %d envelope differs", index)
+		}
+	}
+	metadataA, metadataB := blockA.GetMetadata().GetMetadata(), blockB.GetMetadata().GetMetadata()
+	for index := 0; index < len(metadataA) || index < len(metadataB); index++ {
+		var valueA, valueB []byte
+		if index < len(metadataA) {
+			valueA = metadataA[index]
+		}
+		if index < len(metadataB) {
+			valueB = metadataB[index]
+		}
+		if bytes.Equal(valueA, valueB) {
+			continue
+		}
+		if index == int(commonpb.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+			for txIndex := 0; txIndex < len(valueA) && txIndex < len(valueB); txIndex++ {
+				if valueA[txIndex] != valueB[txIndex] {
+					addDiff("transaction %d validation code %s != %s", txIndex, ValidationCodeName(int32(valueA[txIndex])), ValidationCodeName(int32(valueB[txIndex])))
+				}
+			}
+			continue
+		}
+		addDiff("metadata %s differs", commonpb.BlockMetadataIndex_name[int32(index)])
+	}
+	diff.Identical = len(diff.Differences) == 0
+	return diff
+}
@@ -0,0 +1,290 @@
+package fabricgosdkclientcore
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+)
+
+//Config group names of a channel configuration
+const (
+	ConfigGroupApplication = "Application"
+	ConfigGroupOrderer     = "Orderer"
+)
+
+//LedgerInfo is the height and the latest block hashes of a channel ledger
+type LedgerInfo struct {
+	Height            uint64 `json:"height"`
+	CurrentBlockHash  string `json:"currentBlockHash"`
+	PreviousBlockHash string `json:"previousBlockHash"`
+	Endorser          string `json:"endorser,omitempty"`
+}
+
+//DecodedConfig is the summary of a channel configuration transaction
+type DecodedConfig struct {
+	Sequence      uint64                `json:"sequence"`
+	Organizations []*ConfigOrganization `json:"organizations"`
+	UpdatedPaths  []string              `json:"updatedPaths,omitempty"`
+}
+
+//ConfigOrganization is an organization of the application or orderer group of a channel configuration
+type ConfigOrganization struct {
+	Group string `json:"group"`
+	Name  string `json:"name"`
+	MSPID string `json:"mspId"`
+}
+
+//asn1BlockHeader is the form in which fabric hashes a block header
+type asn1BlockHeader struct {
+	Number       *big.Int
+	PreviousHash []byte
+	DataHash     []byte
+}
+
+//BlockHeaderBytes returns the ASN.1 encoding of a block header, which is hashed and signed by the orderer
+func BlockHeaderBytes(header *commonpb.BlockHeader) []byte {
+	headerBytes, err := asn1.Marshal(asn1BlockHeader{
+		Number:       new(big.Int).SetUint64(header.Number),
+		PreviousHash: header.PreviousHash,
+		DataHash:     header.DataHash,
+	})
+	if err != nil {
+		//The header holds only a number and byte slices, marshalling cannot fail
+		panic(err)
+	}
+	return headerBytes
+}
+
+//BlockHeaderHash returns the hash of a block header, which the next block refers to as its previous hash
+func BlockHeaderHash(header *commonpb.BlockHeader) []byte {
+	hash := sha256.Sum256(BlockHeaderBytes(header))
+	return hash[:]
+}
+
+//decodeConfigTransaction decodes the config envelope of a configuration transaction
+func decodeConfigTransaction(data []byte) (*DecodedConfig, error) {
+	configEnvelope := new(commonpb.ConfigEnvelope)
+	if err := proto.Unmarshal(data, configEnvelope); err != nil {
+		return nil, fmt.Errorf("Invalid config envelope: %v", err)
+	}
+	if configEnvelope.Config == nil {
+		return nil, fmt.Errorf("Config envelope without config")
+	}
+	decoded := &DecodedConfig{
+		Sequence:      configEnvelope.Config.Sequence,
+		Organizations: configOrganizations(configEnvelope.Config.ChannelGroup),
+	}
+	if configEnvelope.LastUpdate != nil {
+		configUpdate, err := decodeConfigUpdateEnvelope(configEnvelope.LastUpdate)
+		if err != nil {
+			return nil, err
+		}
+		decoded.UpdatedPaths = configUpdatePaths(configUpdate.ReadSet, configUpdate.WriteSet, "")
+		sort.Strings(decoded.UpdatedPaths)
+	}
+	return decoded, nil
+}
+
+//decodeConfigUpdateEnvelope returns the config update carried in the last update envelope
+func decodeConfigUpdateEnvelope(envelope *commonpb.Envelope) (*commonpb.ConfigUpdate, error) {
+	payload := new(commonpb.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, fmt.Errorf("Invalid config update payload: %v", err)
+	}
+	updateEnvelope := new(commonpb.ConfigUpdateEnvelope)
+	if err := proto.Unmarshal(payload.Data, updateEnvelope); err != nil {
+		return nil, fmt.Errorf("Invalid config update envelope: %v", err)
+	}
+	configUpdate := new(commonpb.ConfigUpdate)
+	if err := proto.Unmarshal(updateEnvelope.ConfigUpdate, configUpdate); err != nil {
+		return nil, fmt.Errorf("Invalid config update: %v", err)
+	}
+	return configUpdate, nil
+}
+
+//configOrganizations lists the organizations of the application and orderer groups
+func configOrganizations(channelGroup *commonpb.ConfigGroup) []*ConfigOrganization {
+	organizations := make([]*ConfigOrganization, 0)
+	for _, groupName := range []string{ConfigGroupApplication, ConfigGroupOrderer} {
+		group := channelGroup.GetGroups()[groupName]
+		for _, orgName := range sortedGroupNames(group) {
+			organizations = append(organizations, &ConfigOrganization{Group: groupName, Name: orgName, MSPID: orgMSPID(group.Groups[orgName])})
+		}
+	}
+	return organizations
+}
+
+//orgMSPConfig returns the fabric MSP configuration of an organization group
+func orgMSPConfig(orgGroup *commonpb.ConfigGroup) *mspprotos.FabricMSPConfig {
+	value := orgGroup.GetValues()["MSP"]
+	if value == nil {
+		return nil
+	}
+	mspConfig := new(mspprotos.MSPConfig)
+	if err := proto.Unmarshal(value.Value, mspConfig); err != nil {
+		return nil
+	}
+	fabricMSPConfig := new(mspprotos.FabricMSPConfig)
+	if err := proto.Unmarshal(mspConfig.Config, fabricMSPConfig); err != nil {
+		return nil
+	}
+	return fabricMSPConfig
+}
+
+func orgMSPID(orgGroup *commonpb.ConfigGroup) string {
+	if fabricMSPConfig := orgMSPConfig(orgGroup); fabricMSPConfig != nil {
+		return fabricMSPConfig.Name
+	}
+	return ""
+}
+
+func sortedGroupNames(group *commonpb.ConfigGroup) []string {
+	names := make([]string, 0, len(group.GetGroups()))
+	for name := range group.GetGroups() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//configUpdatePaths lists the groups, values and policies of the write set which are new or
+//have a different version than in the read set. Paths below a new group are not listed.
+func configUpdatePaths(readSet, writeSet *commonpb.ConfigGroup, prefix string) []string {
+	paths := make([]string, 0)
+	if writeSet == nil {
+		return paths
+	}
+	for name, group := range writeSet.Groups {
+		path := prefix + "/" + name
+		readGroup := readSet.GetGroups()[name]
+		if readGroup == nil {
+			//A new group, everything below it is new as well
+			paths = append(paths, path)
+			continue
+		}
+		if readGroup.Version != group.Version {
+			paths = append(paths, path)
+		}
+		paths = append(paths, configUpdatePaths(readGroup, group, path)...)
+	}
+	for name, value := range writeSet.Values {
+		if readValue := readSet.GetValues()[name]; readValue == nil || readValue.Version != value.Version {
+			paths = append(paths, prefix+"/values/"+name)
+		}
+	}
+	for name, policy := range writeSet.Policies {
+		if readPolicy := readSet.GetPolicies()[name]; readPolicy == nil || readPolicy.Version != policy.Version {
+			paths = append(paths, prefix+"/policies/"+name)
+		}
+	}
+	return paths
+}
+
+//GetLedgerInfo returns the height and the current and previous block hashes of the channel ledger
+func (fsc *FabricSDKClient) GetLedgerInfo(channel string) (*LedgerInfo, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	ledgerClient, err := fsc.getLedgerClient(channel)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ledgerClient.QueryInfo()
+	if err != nil {
+		return nil, fmt.Errorf("Error in querying ledger info of %s: %v", channel, err)
+	}
+	return newLedgerInfo(info), nil
+}
+
+func newLedgerInfo(info *fab.BlockchainInfoResponse) *LedgerInfo {
+	return &LedgerInfo{
+		Height:            info.BCI.GetHeight(),
+		CurrentBlockHash:  hex.EncodeToString(info.BCI.GetCurrentBlockHash()),
+		PreviousBlockHash: hex.EncodeToString(info.BCI.GetPreviousBlockHash()),
+		Endorser:          info.Endorser,
+	}
+}
+
+//GetBlockByHash returns the decoded block with the given hex encoded header hash
+func (fsc *FabricSDKClient) GetBlockByHash(channel, blockHash string) (*DecodedBlock, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	hash, err := hex.DecodeString(blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid block hash %s: %v", blockHash, err)
+	}
+	ledgerClient, err := fsc.getLedgerClient(channel)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ledgerClient.QueryBlockByHash(hash)
+	if err != nil {
+		return nil, fmt.Errorf("Error in retriving the block %s: %v", blockHash, err)
+	}
+	return DecodeBlock(block)
+}
+
+//GetBlockByTxID returns the decoded block containing the transaction
+func (fsc *FabricSDKClient) GetBlockByTxID(channel, txID string) (*DecodedBlock, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	ledgerClient, err := fsc.getLedgerClient(channel)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ledgerClient.QueryBlockByTxID(fab.TransactionID(txID))
+	if err != nil {
+		return nil, fmt.Errorf("Error in retriving the block of transaction %s: %v", txID, err)
+	}
+	return DecodeBlock(block)
+}
+
+//TransactionDetails is a decoded transaction with the number of the block it was committed in
+type TransactionDetails struct {
+	*DecodedTransaction
+	BlockNumber uint64 `json:"blockNumber"`
+}
+
+//GetTransaction returns the decoded transaction with its validation code and block number
+func (fsc *FabricSDKClient) GetTransaction(channel, txID string) (*TransactionDetails, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	ledgerClient, err := fsc.getLedgerClient(channel)
+	if err != nil {
+		return nil, err
+	}
+	processedTx, err := ledgerClient.QueryTransaction(fab.TransactionID(txID))
+	if err != nil {
+		return nil, fmt.Errorf("Error in retriving the transaction %s: %v", txID, err)
+	}
+	block, err := ledgerClient.QueryBlockByTxID(fab.TransactionID(txID))
+	if err != nil {
+		return nil, fmt.Errorf("Error in retriving the block of transaction %s: %v", txID, err)
+	}
+	decodedBlock, err := DecodeBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	//A rejected duplicate has the same ID, the validation code of the processed transaction tells them apart
+	for _, transaction := range decodedBlock.Transactions {
+		if transaction.TxID == txID && transaction.ValidationCode == processedTx.ValidationCode {
+			return &TransactionDetails{DecodedTransaction: transaction, BlockNumber: decodedBlock.Number}, nil
+		}
+	}
+	return nil, fmt.Errorf("Transaction %s not found in block %d", txID, decodedBlock.Number)
+}
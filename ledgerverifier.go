@@ -0,0 +1,362 @@
+package fabricgosdkclientcore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/golang/protobuf/proto"
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+)
+
+//BrokenLink is the first block of a ledger which failed the verification
+type BrokenLink struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	Reason      string `json:"reason"`
+}
+
+func (bl *BrokenLink) Error() string {
+	return fmt.Sprintf("Block %d: %s", bl.BlockNumber, bl.Reason)
+}
+
+//LedgerVerification is the result of a ledger verification
+type LedgerVerification struct {
+	Valid          bool        `json:"valid"`
+	FirstBlock     uint64      `json:"firstBlock"`
+	LastBlock      uint64      `json:"lastBlock"`
+	BlocksVerified uint64      `json:"blocksVerified"`
+	FirstBroken    *BrokenLink `json:"firstBroken,omitempty"`
+}
+
+//LedgerVerifier verifies blocks given in increasing order. It recomputes the data hash of each
+//block, checks that its previous hash is the header hash of the block before and verifies the
+//orderer signatures against the orderer MSPs of the channel config. The channel config is taken
+//from the config blocks verified, a range not starting at the genesis block needs the config
+//block it refers to through SetChannelConfig.
+type LedgerVerifier struct {
+	ordererMSPs map[string]*x509.VerifyOptions
+	previous    *commonpb.BlockHeader
+	result      *LedgerVerification
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+//NewLedgerVerifier creates a verifier
+func NewLedgerVerifier() *LedgerVerifier {
+	return &LedgerVerifier{
+		ordererMSPs: make(map[string]*x509.VerifyOptions),
+		result:      &LedgerVerification{Valid: true},
+	}
+}
+
+//SetChannelConfig loads the orderer MSPs from a config block
+func (lv *LedgerVerifier) SetChannelConfig(configBlock *commonpb.Block) error {
+	config, err := blockChannelConfig(configBlock)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return fmt.Errorf("Block %d is not a config block", configBlock.GetHeader().GetNumber())
+	}
+	return lv.loadOrdererMSPs(config)
+}
+
+//Verify verifies the next block. The returned error is a *BrokenLink once a block failed, the
+//following blocks are not verified.
+func (lv *LedgerVerifier) Verify(block *commonpb.Block) error {
+	if lv.result.FirstBroken != nil {
+		return lv.result.FirstBroken
+	}
+	if reason := lv.verifyBlock(block); len(reason) > 0 {
+		lv.result.Valid = false
+		lv.result.FirstBroken = &BrokenLink{BlockNumber: block.GetHeader().GetNumber(), Reason: reason}
+		return lv.result.FirstBroken
+	}
+	if lv.result.BlocksVerified == 0 {
+		lv.result.FirstBlock = block.Header.Number
+	}
+	lv.result.LastBlock = block.Header.Number
+	lv.result.BlocksVerified++
+	lv.previous = block.Header
+	return nil
+}
+
+//Result returns the verification of the blocks given so far
+func (lv *LedgerVerifier) Result() *LedgerVerification {
+	return lv.result
+}
+
+func (lv *LedgerVerifier) verifyBlock(block *commonpb.Block) string {
+	if block == nil || block.Header == nil || block.Data == nil {
+		return "block without header or data"
+	}
+	dataHash := sha256.New()
+	for _, data := range block.Data.Data {
+		dataHash.Write(data)
+	}
+	if !bytes.Equal(dataHash.Sum(nil), block.Header.DataHash) {
+		return "data hash does not match the block data"
+	}
+	if lv.previous != nil {
+		if block.Header.Number != lv.previous.Number+1 {
+			return fmt.Sprintf("expected block %d", lv.previous.Number+1)
+		}
+		if !bytes.Equal(BlockHeaderHash(lv.previous), block.Header.PreviousHash) {
+			return fmt.Sprintf("previous hash does not match the header hash of block %d", lv.previous.Number)
+		}
+	}
+	//The genesis block is created offline and carries no orderer signature
+	if block.Header.Number > 0 {
+		if reason := lv.verifyOrdererSignatures(block); len(reason) > 0 {
+			return reason
+		}
+	}
+	config, err := blockChannelConfig(block)
+	if err != nil {
+		return err.Error()
+	}
+	if config != nil {
+		if err := lv.loadOrdererMSPs(config); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+func (lv *LedgerVerifier) verifyOrdererSignatures(block *commonpb.Block) string {
+	if len(lv.ordererMSPs) == 0 {
+		return "no orderer MSP known to verify the signatures, the channel config is missing"
+	}
+	metadata := new(commonpb.Metadata)
+	if len(block.GetMetadata().GetMetadata()) > int(commonpb.BlockMetadataIndex_SIGNATURES) {
+		if err := proto.Unmarshal(block.Metadata.Metadata[commonpb.BlockMetadataIndex_SIGNATURES], metadata); err != nil {
+			return fmt.Sprintf("invalid signature metadata: %v", err)
+		}
+	}
+	if len(metadata.Signatures) == 0 {
+		return "block is not signed by the orderer"
+	}
+	headerBytes := BlockHeaderBytes(block.Header)
+	for _, metadataSignature := range metadata.Signatures {
+		signatureHeader := new(commonpb.SignatureHeader)
+		if err := proto.Unmarshal(metadataSignature.SignatureHeader, signatureHeader); err != nil {
+			return fmt.Sprintf("invalid signature header: %v", err)
+		}
+		signedBytes := make([]byte, 0, len(metadata.Value)+len(metadataSignature.SignatureHeader)+len(headerBytes))
+		signedBytes = append(signedBytes, metadata.Value...)
+		signedBytes = append(signedBytes, metadataSignature.SignatureHeader...)
+		signedBytes = append(signedBytes, headerBytes...)
+		if err := lv.verifySignature(signatureHeader.Creator, signedBytes, metadataSignature.Signature); err != nil {
+			return fmt.Sprintf("invalid orderer signature: %v", err)
+		}
+	}
+	return ""
+}
+
+//verifySignature checks that the creator is issued by an orderer MSP and signed the bytes.
+//Certificates are checked at their issuance time, an orderer certificate expired since the
+//block was signed does not invalidate it.
+func (lv *LedgerVerifier) verifySignature(creator, signedBytes, signature []byte) error {
+	identity := new(mspprotos.SerializedIdentity)
+	if err := proto.Unmarshal(creator, identity); err != nil {
+		return fmt.Errorf("invalid creator: %v", err)
+	}
+	verifyOptions, isFound := lv.ordererMSPs[identity.Mspid]
+	if !isFound {
+		return fmt.Errorf("%s is not an orderer MSP of the channel", identity.Mspid)
+	}
+	certificate, err := parsePEMCertificate(identity.IdBytes)
+	if err != nil {
+		return err
+	}
+	options := *verifyOptions
+	options.CurrentTime = certificate.NotBefore
+	if _, err := certificate.Verify(options); err != nil {
+		return fmt.Errorf("certificate %s is not issued by %s: %v", certificate.Subject.CommonName, identity.Mspid, err)
+	}
+	publicKey, ok := certificate.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported public key of %s", certificate.Subject.CommonName)
+	}
+	ecdsaSig := new(ecdsaSignature)
+	if _, err := asn1.Unmarshal(signature, ecdsaSig); err != nil {
+		return fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	digest := sha256.Sum256(signedBytes)
+	if !ecdsa.Verify(publicKey, digest[:], ecdsaSig.R, ecdsaSig.S) {
+		return fmt.Errorf("signature of %s does not match", certificate.Subject.CommonName)
+	}
+	return nil
+}
+
+//loadOrdererMSPs replaces the orderer MSPs with the ones of the channel config
+func (lv *LedgerVerifier) loadOrdererMSPs(config *commonpb.Config) error {
+	ordererGroup := config.GetChannelGroup().GetGroups()[ConfigGroupOrderer]
+	ordererMSPs := make(map[string]*x509.VerifyOptions)
+	for _, orgName := range sortedGroupNames(ordererGroup) {
+		fabricMSPConfig := orgMSPConfig(ordererGroup.Groups[orgName])
+		if fabricMSPConfig == nil {
+			return fmt.Errorf("Orderer organization %s without MSP config", orgName)
+		}
+		verifyOptions := &x509.VerifyOptions{
+			Roots:         x509.NewCertPool(),
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}
+		for _, rootCert := range fabricMSPConfig.RootCerts {
+			verifyOptions.Roots.AppendCertsFromPEM(rootCert)
+		}
+		for _, intermediateCert := range fabricMSPConfig.IntermediateCerts {
+			verifyOptions.Intermediates.AppendCertsFromPEM(intermediateCert)
+		}
+		ordererMSPs[fabricMSPConfig.Name] = verifyOptions
+	}
+	if len(ordererMSPs) == 0 {
+		return fmt.Errorf("Channel config without orderer organizations")
+	}
+	lv.ordererMSPs = ordererMSPs
+	return nil
+}
+
+//blockChannelConfig returns the channel config of a config block, nil for any other block
+func blockChannelConfig(block *commonpb.Block) (*commonpb.Config, error) {
+	if len(block.GetData().GetData()) != 1 {
+		return nil, nil
+	}
+	envelope := new(commonpb.Envelope)
+	if err := proto.Unmarshal(block.Data.Data[0], envelope); err != nil {
+		return nil, fmt.Errorf("Invalid envelope: %v", err)
+	}
+	payload := new(commonpb.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, fmt.Errorf("Invalid payload: %v", err)
+	}
+	channelHeader := new(commonpb.ChannelHeader)
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), channelHeader); err != nil {
+		return nil, fmt.Errorf("Invalid channel header: %v", err)
+	}
+	if channelHeader.Type != int32(commonpb.HeaderType_CONFIG) {
+		return nil, nil
+	}
+	configEnvelope := new(commonpb.ConfigEnvelope)
+	if err := proto.Unmarshal(payload.Data, configEnvelope); err != nil {
+		return nil, fmt.Errorf("Invalid config envelope: %v", err)
+	}
+	return configEnvelope.Config, nil
+}
+
+//LastConfigIndex returns the number of the config block in force for a block
+func LastConfigIndex(block *commonpb.Block) (uint64, error) {
+	if len(block.GetMetadata().GetMetadata()) <= int(commonpb.BlockMetadataIndex_LAST_CONFIG) {
+		return 0, fmt.Errorf("Block without last config metadata")
+	}
+	metadata := new(commonpb.Metadata)
+	if err := proto.Unmarshal(block.Metadata.Metadata[commonpb.BlockMetadataIndex_LAST_CONFIG], metadata); err != nil {
+		return 0, fmt.Errorf("Invalid last config metadata: %v", err)
+	}
+	lastConfig := new(commonpb.LastConfig)
+	if err := proto.Unmarshal(metadata.Value, lastConfig); err != nil {
+		return 0, fmt.Errorf("Invalid last config: %v", err)
+	}
+	return lastConfig.Index, nil
+}
+
+//VerifyBlocks verifies the blocks from block number from to block number to fetched by fetcher.
+//The config block in force for the first block is fetched unless the range starts at the genesis block.
+func VerifyBlocks(fetcher BlockFetcher, from, to uint64, concurrency int) (*LedgerVerification, error) {
+	if from > to {
+		return nil, fmt.Errorf("Blocks are verified in increasing order, %d is after %d", from, to)
+	}
+	verifier := NewLedgerVerifier()
+	if from > 0 {
+		if err := loadConfigForBlock(verifier, fetcher, from); err != nil {
+			return nil, err
+		}
+	}
+	iterator := NewBlockIterator(fetcher, from, to, concurrency)
+	defer iterator.Close()
+	for count := uint64(0); count < iterator.Len(); count++ {
+		block, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if verifier.Verify(block) != nil {
+			break
+		}
+	}
+	return verifier.Result(), nil
+}
+
+func loadConfigForBlock(verifier *LedgerVerifier, fetcher BlockFetcher, blockNumber uint64) error {
+	block, err := fetcher(blockNumber)
+	if err != nil {
+		return fmt.Errorf("Error in retriving the block %d: %v", blockNumber, err)
+	}
+	configIndex, err := LastConfigIndex(block)
+	if err != nil {
+		return err
+	}
+	//A config block is signed under the config in force before it, not the one it introduces
+	if configIndex == blockNumber && blockNumber > 0 {
+		return loadConfigForBlock(verifier, fetcher, blockNumber-1)
+	}
+	configBlock, err := fetcher(configIndex)
+	if err != nil {
+		return fmt.Errorf("Error in retriving the config block %d: %v", configIndex, err)
+	}
+	return verifier.SetChannelConfig(configBlock)
+}
+
+//VerifyLedger verifies the hash chain and the orderer signatures of the channel ledger from block
+//number from to block number to
+func (fsc *FabricSDKClient) VerifyLedger(channel string, from, to uint64) (*LedgerVerification, error) {
+	fetcher, err := fsc.ledgerBlockFetcher(channel)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyBlocks(fetcher, from, to, DefaultBlockFetchConcurrency)
+}
+
+//VerifyExport verifies a protobuf ledger export. configBlock is the config block in force for the
+//first exported block and may be nil when the export starts at the genesis block. The first and
+//last block hashes of each file are checked against the manifest as well.
+func VerifyExport(dir string, configBlock *commonpb.Block) (*LedgerVerification, error) {
+	manifest, err := ReadExportManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.From > manifest.To {
+		return nil, fmt.Errorf("Backward exports cannot be verified")
+	}
+	verifier := NewLedgerVerifier()
+	if configBlock != nil {
+		if err := verifier.SetChannelConfig(configBlock); err != nil {
+			return nil, err
+		}
+	}
+	manifestHashes := make(map[uint64]string)
+	for _, exportFile := range manifest.Files {
+		manifestHashes[exportFile.FirstBlock] = exportFile.FirstBlockHash
+		manifestHashes[exportFile.LastBlock] = exportFile.LastBlockHash
+	}
+	err = ReadExportedBlocks(dir, func(block *commonpb.Block) error {
+		if expectedHash, isFound := manifestHashes[block.GetHeader().GetNumber()]; isFound && expectedHash != hex.EncodeToString(BlockHeaderHash(block.Header)) {
+			verifier.result.Valid = false
+			verifier.result.FirstBroken = &BrokenLink{BlockNumber: block.Header.Number, Reason: "header hash does not match the manifest"}
+			return verifier.result.FirstBroken
+		}
+		return verifier.Verify(block)
+	})
+	if _, isBroken := err.(*BrokenLink); err != nil && !isBroken {
+		return nil, err
+	}
+	return verifier.Result(), nil
+}
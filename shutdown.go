@@ -0,0 +1,95 @@
+package fabricgosdkclientcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+//ErrClientShutdown is returned for requests made after Shutdown was called
+var ErrClientShutdown = errors.New("Client is shut down")
+
+//Shutdown shuts the client down. It stops accepting new queries, invocations and event
+//registrations, waits for the in-flight Query and InvokeTrxn calls, unregisters every event
+//subscription, waits for the events already received to reach the event sinks, saves the
+//checkpoints, closes the sinks and finally closes the SDK.
+//An error is returned if ctx is done before the in-flight work completed, the resources are
+//released in any case.
+func (fsc *FabricSDKClient) Shutdown(ctx context.Context) error {
+	fsc.lifecycleLock.Lock()
+	if fsc.isShutdown {
+		fsc.lifecycleLock.Unlock()
+		return nil
+	}
+	fsc.isShutdown = true
+	fsc.lifecycleLock.Unlock()
+	_logger.Info("Shutting down the client")
+
+	fsc.StopCertExpiryMonitor()
+	var shutdownErr error
+	if err := waitWithContext(ctx, &fsc.inFlight); err != nil {
+		shutdownErr = fmt.Errorf("In-flight requests did not complete: %v", err)
+	}
+	fsc.deregisterAllEvents()
+	if err := waitWithContext(ctx, &fsc.forwarders); err != nil && shutdownErr == nil {
+		shutdownErr = fmt.Errorf("Event forwarding did not complete: %v", err)
+	}
+	fsc.closeEventSinks()
+	if sdk := fsc.getSDK(); sdk != nil {
+		sdk.Close()
+	}
+	if shutdownErr != nil {
+		_logger.Errorf("Shutdown incomplete %+v", shutdownErr)
+		return shutdownErr
+	}
+	_logger.Info("Shutdown complete")
+	return nil
+}
+
+//beginWork registers an in-flight request unless the client is shut down
+func (fsc *FabricSDKClient) beginWork() error {
+	fsc.lifecycleLock.Lock()
+	defer fsc.lifecycleLock.Unlock()
+	if fsc.isShutdown {
+		return ErrClientShutdown
+	}
+	fsc.inFlight.Add(1)
+	return nil
+}
+
+//endWork marks an in-flight request as complete
+func (fsc *FabricSDKClient) endWork() {
+	fsc.inFlight.Done()
+}
+
+//deregisterAllEvents unregisters every event subscription in the registry
+func (fsc *FabricSDKClient) deregisterAllEvents() {
+	fsc.eventSubsLock.Lock()
+	registrations := make([]EventWaitGroup, 0, len(fsc.eventSubsReg))
+	for eventName, evtWtGrp := range fsc.eventSubsReg {
+		registrations = append(registrations, evtWtGrp)
+		delete(fsc.eventSubsReg, eventName)
+	}
+	fsc.eventSubsLock.Unlock()
+	for _, evtWtGrp := range registrations {
+		_logger.Infof("Deregistering %s", evtWtGrp.eventName)
+		evtWtGrp.Deregister()
+		fsc.removeEventHandler(evtWtGrp.eventName)
+	}
+}
+
+//waitWithContext waits for the wait group until ctx is done
+func waitWithContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
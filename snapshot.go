@@ -0,0 +1,235 @@
+package fabricgosdkclientcore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//DefaultSnapshotPageSize is the number of keys asked per query when no page size is configured
+const DefaultSnapshotPageSize = 100
+
+//SnapshotRecord is one key of the world state with its value. A value which is not JSON is
+//kept as a JSON string.
+type SnapshotRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+//SnapshotPage is one page of records returned by the query function with the bookmark of the
+//next page. An empty bookmark ends the snapshot.
+type SnapshotPage struct {
+	Records  []*SnapshotRecord `json:"records"`
+	Bookmark string            `json:"bookmark"`
+}
+
+//SnapshotPageDecoder decodes the payload returned by the query function
+type SnapshotPageDecoder func(payload []byte) (*SnapshotPage, error)
+
+//SnapshotConfig describes the chain code function walking the world state. The function is
+//called with Args followed by the page size and the bookmark, empty for the first page.
+type SnapshotConfig struct {
+	Function    string
+	Args        [][]byte
+	PageSize    int
+	TargetPeers []string
+	//DecodePage defaults to the JSON encoding of SnapshotPage
+	DecodePage SnapshotPageDecoder
+}
+
+//Snapshot is a dump of the keys of a chain code. The ledger height is read before and after the
+//walk, the snapshot is consistent when no block was committed meanwhile.
+type Snapshot struct {
+	Channel     string            `json:"channel"`
+	ChaincodeID string            `json:"chaincodeId"`
+	TakenAt     time.Time         `json:"takenAt"`
+	StartHeight uint64            `json:"startHeight"`
+	EndHeight   uint64            `json:"endHeight"`
+	Consistent  bool              `json:"consistent"`
+	RecordCount int               `json:"recordCount"`
+	Records     []*SnapshotRecord `json:"-"`
+}
+
+//SnapshotChange is a key whose value differs between two snapshots
+type SnapshotChange struct {
+	Key      string          `json:"key"`
+	OldValue json.RawMessage `json:"oldValue"`
+	NewValue json.RawMessage `json:"newValue"`
+}
+
+//SnapshotDiff lists the keys added, removed and changed from an old to a new snapshot
+type SnapshotDiff struct {
+	Added   []*SnapshotRecord `json:"added"`
+	Removed []*SnapshotRecord `json:"removed"`
+	Changed []*SnapshotChange `json:"changed"`
+}
+
+//NewSnapshotRecord creates a record for a value of the world state, for page decoders
+func NewSnapshotRecord(key string, value []byte) *SnapshotRecord {
+	return &SnapshotRecord{Key: key, Value: snapshotValue(value)}
+}
+
+//snapshotValue keeps a JSON value as is and encodes any other value as a JSON string
+func snapshotValue(value []byte) json.RawMessage {
+	if json.Valid(value) {
+		return json.RawMessage(value)
+	}
+	encoded, _ := json.Marshal(string(value))
+	return json.RawMessage(encoded)
+}
+
+func decodeJSONSnapshotPage(payload []byte) (*SnapshotPage, error) {
+	page := new(SnapshotPage)
+	if err := json.Unmarshal(payload, page); err != nil {
+		return nil, fmt.Errorf("Invalid snapshot page: %v", err)
+	}
+	return page, nil
+}
+
+//TakeSnapshot walks all the keys of the chain code through the configured query function
+func (fsc *FabricSDKClient) TakeSnapshot(channel, user, ccID string, config SnapshotConfig) (*Snapshot, error) {
+	startInfo, err := fsc.GetLedgerInfo(channel)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{Channel: channel, ChaincodeID: ccID, TakenAt: time.Now(), StartHeight: startInfo.Height}
+	query := func(args [][]byte) ([]byte, error) {
+		payload, _, err := fsc.Query(channel, user, ccID, config.Function, args, config.TargetPeers, nil)
+		return payload, err
+	}
+	if err := CollectSnapshot(snapshot, query, config); err != nil {
+		return nil, err
+	}
+	endInfo, err := fsc.GetLedgerInfo(channel)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.EndHeight = endInfo.Height
+	snapshot.Consistent = snapshot.StartHeight == snapshot.EndHeight
+	if !snapshot.Consistent {
+		_logger.Warningf("Ledger of %s moved from height %d to %d during the snapshot of %s", channel, snapshot.StartHeight, snapshot.EndHeight, ccID)
+	}
+	return snapshot, nil
+}
+
+//CollectSnapshot adds the records of all the pages returned by query to the snapshot
+func CollectSnapshot(snapshot *Snapshot, query func(args [][]byte) ([]byte, error), config SnapshotConfig) error {
+	pageSize := config.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultSnapshotPageSize
+	}
+	decodePage := config.DecodePage
+	if decodePage == nil {
+		decodePage = decodeJSONSnapshotPage
+	}
+	bookmark := ""
+	for {
+		args := append(append([][]byte{}, config.Args...), []byte(strconv.Itoa(pageSize)), []byte(bookmark))
+		payload, err := query(args)
+		if err != nil {
+			return fmt.Errorf("Error in querying the page after bookmark %q: %v", bookmark, err)
+		}
+		page, err := decodePage(payload)
+		if err != nil {
+			return err
+		}
+		for _, record := range page.Records {
+			snapshot.Records = append(snapshot.Records, NewSnapshotRecord(record.Key, record.Value))
+		}
+		if len(page.Bookmark) == 0 || len(page.Records) == 0 {
+			break
+		}
+		if page.Bookmark == bookmark {
+			return fmt.Errorf("Query function returned the same bookmark %q again", bookmark)
+		}
+		bookmark = page.Bookmark
+	}
+	snapshot.RecordCount = len(snapshot.Records)
+	return nil
+}
+
+//WriteSnapshot writes the snapshot as JSON lines, the snapshot details followed by one line per record
+func WriteSnapshot(path string, snapshot *Snapshot) error {
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	if err := encoder.Encode(snapshot); err != nil {
+		return err
+	}
+	for _, record := range snapshot.Records {
+		if err := encoder.Encode(NewSnapshotRecord(record.Key, record.Value)); err != nil {
+			return fmt.Errorf("Unable to encode %s: %v", record.Key, err)
+		}
+	}
+	if err := writeFileAtomic(path, buffer.Bytes()); err != nil {
+		return fmt.Errorf("Unable to write snapshot %s: %v", path, err)
+	}
+	return nil
+}
+
+//ReadSnapshot reads a snapshot written by WriteSnapshot
+func ReadSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read snapshot %s: %v", path, err)
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	snapshot := new(Snapshot)
+	if err := decoder.Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("Invalid snapshot %s: %v", path, err)
+	}
+	snapshot.Records = make([]*SnapshotRecord, 0, snapshot.RecordCount)
+	for decoder.More() {
+		record := new(SnapshotRecord)
+		if err := decoder.Decode(record); err != nil {
+			return nil, fmt.Errorf("Invalid snapshot record in %s: %v", path, err)
+		}
+		snapshot.Records = append(snapshot.Records, record)
+	}
+	if len(snapshot.Records) != snapshot.RecordCount {
+		return nil, fmt.Errorf("Snapshot %s has %d records instead of %d", path, len(snapshot.Records), snapshot.RecordCount)
+	}
+	return snapshot, nil
+}
+
+//DiffSnapshots lists the keys added, removed and changed from the old to the new snapshot, sorted by key
+func DiffSnapshots(oldSnapshot, newSnapshot *Snapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{Added: make([]*SnapshotRecord, 0), Removed: make([]*SnapshotRecord, 0), Changed: make([]*SnapshotChange, 0)}
+	oldRecords := make(map[string]*SnapshotRecord, len(oldSnapshot.Records))
+	for _, record := range oldSnapshot.Records {
+		oldRecords[record.Key] = record
+	}
+	for _, record := range newSnapshot.Records {
+		oldRecord, isFound := oldRecords[record.Key]
+		if !isFound {
+			diff.Added = append(diff.Added, NewSnapshotRecord(record.Key, record.Value))
+			continue
+		}
+		delete(oldRecords, record.Key)
+		if !sameJSON(oldRecord.Value, record.Value) {
+			diff.Changed = append(diff.Changed, &SnapshotChange{Key: record.Key, OldValue: snapshotValue(oldRecord.Value), NewValue: snapshotValue(record.Value)})
+		}
+	}
+	for _, record := range oldRecords {
+		diff.Removed = append(diff.Removed, NewSnapshotRecord(record.Key, record.Value))
+	}
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Key < diff.Added[j].Key })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Key < diff.Removed[j].Key })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Key < diff.Changed[j].Key })
+	return diff
+}
+
+//sameJSON compares two JSON values ignoring the white space, a value read back from a snapshot
+//file is compacted
+func sameJSON(first, second json.RawMessage) bool {
+	compactFirst, compactSecond := new(bytes.Buffer), new(bytes.Buffer)
+	if json.Compact(compactFirst, first) != nil || json.Compact(compactSecond, second) != nil {
+		return bytes.Equal(first, second)
+	}
+	return bytes.Equal(compactFirst.Bytes(), compactSecond.Bytes())
+}
@@ -0,0 +1,240 @@
+package fabricgosdkclientcore_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	rwsetpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset"
+	kvrwsetpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	mspprotos "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+//testTx describes a transaction put in a test block
+type testTx struct {
+	txID      string
+	mspID     string
+	certPEM   []byte
+	chaincode string
+	args      []string
+	writes    map[string]string
+	deletes   []string
+	eventName string
+	timestamp time.Time
+}
+
+func newTestCertificate(t *testing.T, commonName string, organizationalUnits ...string) ([]byte, *ecdsa.PrivateKey) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Logf("Error in generating key %v", err)
+		t.FailNow()
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName, OrganizationalUnit: organizationalUnits},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Logf("Error in creating certificate %v", err)
+		t.FailNow()
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), privateKey
+}
+
+func newTestEnvelope(t *testing.T, tx testTx) []byte {
+	kvRWSet := &kvrwsetpb.KVRWSet{}
+	for key, value := range tx.writes {
+		kvRWSet.Writes = append(kvRWSet.Writes, &kvrwsetpb.KVWrite{Key: key, Value: []byte(value)})
+		kvRWSet.Reads = append(kvRWSet.Reads, &kvrwsetpb.KVRead{Key: key})
+	}
+	for _, key := range tx.deletes {
+		kvRWSet.Writes = append(kvRWSet.Writes, &kvrwsetpb.KVWrite{Key: key, IsDelete: true})
+	}
+	chaincodeAction := &pb.ChaincodeAction{
+		Results:     mustMarshal(t, &rwsetpb.TxReadWriteSet{NsRwset: []*rwsetpb.NsReadWriteSet{{Namespace: tx.chaincode, Rwset: mustMarshal(t, kvRWSet)}}}),
+		Response:    &pb.Response{Status: 200},
+		ChaincodeId: &pb.ChaincodeID{Name: tx.chaincode, Version: "1.0"},
+	}
+	if len(tx.eventName) > 0 {
+		chaincodeAction.Events = mustMarshal(t, &pb.ChaincodeEvent{ChaincodeId: tx.chaincode, TxId: tx.txID, EventName: tx.eventName, Payload: []byte("payload")})
+	}
+	args := make([][]byte, 0, len(tx.args))
+	for _, arg := range tx.args {
+		args = append(args, []byte(arg))
+	}
+	invocationSpec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeId: &pb.ChaincodeID{Name: tx.chaincode}, Input: &pb.ChaincodeInput{Args: args}}}
+	actionPayload := &pb.ChaincodeActionPayload{
+		ChaincodeProposalPayload: mustMarshal(t, &pb.ChaincodeProposalPayload{Input: mustMarshal(t, invocationSpec)}),
+		Action: &pb.ChaincodeEndorsedAction{
+			ProposalResponsePayload: mustMarshal(t, &pb.ProposalResponsePayload{Extension: mustMarshal(t, chaincodeAction)}),
+			Endorsements:            []*pb.Endorsement{{Endorser: mustMarshal(t, &mspprotos.SerializedIdentity{Mspid: tx.mspID, IdBytes: tx.certPEM}), Signature: []byte("sig")}},
+		},
+	}
+	transaction := &pb.Transaction{Actions: []*pb.TransactionAction{{Payload: mustMarshal(t, actionPayload)}}}
+	timestamp := tx.timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	protoTimestamp, _ := ptypes.TimestampProto(timestamp)
+	payload := &commonpb.Payload{
+		Header: &commonpb.Header{
+			ChannelHeader:   mustMarshal(t, &commonpb.ChannelHeader{Type: int32(commonpb.HeaderType_ENDORSER_TRANSACTION), ChannelId: "settlementchannel", TxId: tx.txID, Timestamp: protoTimestamp}),
+			SignatureHeader: mustMarshal(t, &commonpb.SignatureHeader{Creator: mustMarshal(t, &mspprotos.SerializedIdentity{Mspid: tx.mspID, IdBytes: tx.certPEM})}),
+		},
+		Data: mustMarshal(t, transaction),
+	}
+	return mustMarshal(t, &commonpb.Envelope{Payload: mustMarshal(t, payload)})
+}
+
+//newTestBlock creates a block with a correct data hash and transaction filter
+func newTestBlock(number uint64, previousHash []byte, envelopes [][]byte, validationCodes []byte) *commonpb.Block {
+	hash := sha256.New()
+	for _, envelope := range envelopes {
+		hash.Write(envelope)
+	}
+	metadata := make([][]byte, 4)
+	metadata[commonpb.BlockMetadataIndex_TRANSACTIONS_FILTER] = validationCodes
+	return &commonpb.Block{
+		Header:   &commonpb.BlockHeader{Number: number, PreviousHash: previousHash, DataHash: hash.Sum(nil)},
+		Data:     &commonpb.BlockData{Data: envelopes},
+		Metadata: &commonpb.BlockMetadata{Metadata: metadata},
+	}
+}
+
+func mustMarshal(t *testing.T, message proto.Message) []byte {
+	bytes, err := proto.Marshal(message)
+	if err != nil {
+		t.Logf("Error in marshalling %v", err)
+		t.FailNow()
+	}
+	return bytes
+}
+
+func Test_DecodeBlock(t *testing.T) {
+	certPEM, _ := newTestCertificate(t, "User1@distributer.net", "client")
+	envelopes := [][]byte{
+		newTestEnvelope(t, testTx{txID: "tx1", mspID: "DistributerMSP", certPEM: certPEM, chaincode: "basic", args: []string{"save", "KEY_1", "VALUE_1"}, writes: map[string]string{"KEY_1": "VALUE_1"}, eventName: "saved"}),
+		newTestEnvelope(t, testTx{txID: "tx2", mspID: "DistributerMSP", certPEM: certPEM, chaincode: "basic", args: []string{"delete", "KEY_1"}, deletes: []string{"KEY_1"}}),
+	}
+	block := newTestBlock(7, []byte{1, 2, 3}, envelopes, []byte{byte(pb.TxValidationCode_VALID), byte(pb.TxValidationCode_MVCC_READ_CONFLICT)})
+	decoded, err := hlfsdkutil.DecodeBlock(block)
+	if err != nil {
+		t.Logf("Error in decoding block %v", err)
+		t.FailNow()
+	}
+	if decoded.Number != 7 || len(decoded.Transactions) != 2 {
+		t.Logf("Unexpected decoded block %+v", decoded)
+		t.FailNow()
+	}
+	first := decoded.Transactions[0]
+	if first.TxID != "tx1" || first.Function != "save" || len(first.Args) != 2 || first.ChaincodeName != "basic" || first.CreatorMSPID != "DistributerMSP" {
+		t.Logf("Unexpected first transaction %+v", first)
+		t.FailNow()
+	}
+	if len(first.Endorsements) != 1 || first.Endorsements[0].EndorserMSPID != "DistributerMSP" || first.ChaincodeResponse == nil || first.ChaincodeResponse.Status != 200 {
+		t.Logf("Unexpected endorsements or response of the first transaction %+v", first)
+		t.FailNow()
+	}
+	if !first.IsValid() || first.ChaincodeEvent == nil || first.ChaincodeEvent.EventName != "saved" {
+		t.Logf("Unexpected validation or event of the first transaction %+v", first)
+		t.FailNow()
+	}
+	if len(first.RWSets) != 1 || first.RWSets[0].Writes[0].Key != "KEY_1" {
+		t.Logf("Unexpected read write set %+v", first.RWSets)
+		t.FailNow()
+	}
+	second := decoded.Transactions[1]
+	if second.IsValid() || second.ValidationCodeName != "MVCC_READ_CONFLICT" || !second.RWSets[0].Writes[0].IsDelete {
+		t.Logf("Unexpected second transaction %+v", second)
+		t.FailNow()
+	}
+}
+
+//newTestConfigEnvelope creates a config transaction envelope with the application and orderer organizations.
+//The orderer MSPs are given with their root certificate, which may be nil.
+func newTestConfigEnvelope(t *testing.T, sequence uint64, applicationMSPs []string, ordererMSPs map[string][]byte) []byte {
+	orgGroups := func(rootCerts map[string][]byte) map[string]*commonpb.ConfigGroup {
+		groups := make(map[string]*commonpb.ConfigGroup)
+		for mspID, rootCert := range rootCerts {
+			fabricMSPConfig := &mspprotos.FabricMSPConfig{Name: mspID}
+			if rootCert != nil {
+				fabricMSPConfig.RootCerts = [][]byte{rootCert}
+			}
+			mspConfig := mustMarshal(t, &mspprotos.MSPConfig{Config: mustMarshal(t, fabricMSPConfig)})
+			groups[mspID] = &commonpb.ConfigGroup{Values: map[string]*commonpb.ConfigValue{"MSP": {Value: mspConfig}}}
+		}
+		return groups
+	}
+	applicationRootCerts := make(map[string][]byte)
+	for _, mspID := range applicationMSPs {
+		applicationRootCerts[mspID] = nil
+	}
+	channelGroup := &commonpb.ConfigGroup{Groups: map[string]*commonpb.ConfigGroup{
+		"Application": {Version: sequence, Groups: orgGroups(applicationRootCerts)},
+		"Orderer":     {Groups: orgGroups(ordererMSPs)},
+	}}
+	configUpdate := &commonpb.ConfigUpdate{
+		ChannelId: "settlementchannel",
+		ReadSet:   &commonpb.ConfigGroup{Groups: map[string]*commonpb.ConfigGroup{"Application": {}}},
+		WriteSet:  &commonpb.ConfigGroup{Groups: map[string]*commonpb.ConfigGroup{"Application": {Version: sequence, Groups: orgGroups(map[string][]byte{applicationMSPs[len(applicationMSPs)-1]: nil})}}},
+	}
+	updatePayload := &commonpb.Payload{Data: mustMarshal(t, &commonpb.ConfigUpdateEnvelope{ConfigUpdate: mustMarshal(t, configUpdate)})}
+	configEnvelope := &commonpb.ConfigEnvelope{
+		Config:     &commonpb.Config{Sequence: sequence, ChannelGroup: channelGroup},
+		LastUpdate: &commonpb.Envelope{Payload: mustMarshal(t, updatePayload)},
+	}
+	payload := &commonpb.Payload{
+		Header: &commonpb.Header{
+			ChannelHeader:   mustMarshal(t, &commonpb.ChannelHeader{Type: int32(commonpb.HeaderType_CONFIG), ChannelId: "settlementchannel"}),
+			SignatureHeader: mustMarshal(t, &commonpb.SignatureHeader{}),
+		},
+		Data: mustMarshal(t, configEnvelope),
+	}
+	return mustMarshal(t, &commonpb.Envelope{Payload: mustMarshal(t, payload)})
+}
+
+func Test_DecodeBlock_HashChainAndConfig(t *testing.T) {
+	first := newTestBlock(0, nil, [][]byte{newTestConfigEnvelope(t, 1, []string{"ManufacturerMSP"}, map[string][]byte{"OrdererMSP": nil})}, []byte{byte(pb.TxValidationCode_VALID)})
+	second := newTestBlock(1, hlfsdkutil.BlockHeaderHash(first.Header), [][]byte{newTestConfigEnvelope(t, 2, []string{"ManufacturerMSP", "DistributerMSP"}, map[string][]byte{"OrdererMSP": nil})}, []byte{byte(pb.TxValidationCode_VALID)})
+	decodedFirst, err := hlfsdkutil.DecodeBlock(first)
+	if err != nil {
+		t.Logf("Error in decoding block %v", err)
+		t.FailNow()
+	}
+	decodedSecond, err := hlfsdkutil.DecodeBlock(second)
+	if err != nil {
+		t.Logf("Error in decoding block %v", err)
+		t.FailNow()
+	}
+	if decodedSecond.PreviousHash != decodedFirst.Hash {
+		t.Logf("Previous hash %s does not match the block hash %s", decodedSecond.PreviousHash, decodedFirst.Hash)
+		t.FailNow()
+	}
+	config := decodedSecond.Transactions[0].Config
+	if config == nil || config.Sequence != 2 || len(config.Organizations) != 3 {
+		t.Logf("Unexpected decoded config %+v", config)
+		t.FailNow()
+	}
+	if config.Organizations[0].MSPID != "DistributerMSP" || config.Organizations[2].Group != "Orderer" {
+		t.Logf("Unexpected config organizations %+v", config.Organizations)
+		t.FailNow()
+	}
+	if len(config.UpdatedPaths) != 2 || config.UpdatedPaths[0] != "/Application" || config.UpdatedPaths[1] != "/Application/DistributerMSP" {
+		t.Logf("Unexpected updated paths %+v", config.UpdatedPaths)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,94 @@
+package fabricgosdkclientcore_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+//newTestChain creates a hash chained ledger of blocks with one transaction each
+func newTestChain(t *testing.T, blockCount int) []*commonpb.Block {
+	certPEM, _ := newTestCertificate(t, "User1@distributer.net", "client")
+	blocks := make([]*commonpb.Block, 0, blockCount)
+	var previousHash []byte
+	for number := 0; number < blockCount; number++ {
+		envelope := newTestEnvelope(t, testTx{txID: fmt.Sprintf("tx%d", number), mspID: "DistributerMSP", certPEM: certPEM, chaincode: "basic", args: []string{"save", "KEY_1"}, writes: map[string]string{"KEY_1": fmt.Sprintf("VALUE_%d", number)}})
+		block := newTestBlock(uint64(number), previousHash, [][]byte{envelope}, []byte{byte(pb.TxValidationCode_VALID)})
+		previousHash = hlfsdkutil.BlockHeaderHash(block.Header)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func Test_BlockIterator_Backward(t *testing.T) {
+	blocks := newTestChain(t, 10)
+	fetcher := func(blockNumber uint64) (*commonpb.Block, error) {
+		return blocks[blockNumber], nil
+	}
+	iterator := hlfsdkutil.NewBlockIterator(fetcher, 9, 0, 3)
+	defer iterator.Close()
+	expected := uint64(9)
+	for {
+		block, err := iterator.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil || block.Header.Number != expected {
+			t.Logf("Unexpected block %+v error %v expected %d", block, err, expected)
+			t.FailNow()
+		}
+		expected--
+	}
+	if expected != ^uint64(0) {
+		t.Logf("Iteration stopped before block 0, next expected %d", expected)
+		t.FailNow()
+	}
+}
+
+func Test_ExportBlocks_Resume(t *testing.T) {
+	exportDir, _ := ioutil.TempDir("", "export")
+	defer os.RemoveAll(exportDir)
+	blocks := newTestChain(t, 8)
+	config := hlfsdkutil.BlockExportConfig{Dir: exportDir, Format: hlfsdkutil.ExportFormatProtobuf, BlocksPerFile: 3, Concurrency: 2}
+	interrupted := func(blockNumber uint64) (*commonpb.Block, error) {
+		if blockNumber == 4 {
+			return nil, fmt.Errorf("peer unavailable")
+		}
+		return blocks[blockNumber], nil
+	}
+	if _, err := hlfsdkutil.ExportBlocks(interrupted, "settlementchannel", 0, 7, config); err == nil {
+		t.Logf("Expected the export to be interrupted")
+		t.FailNow()
+	}
+	manifest, err := hlfsdkutil.ReadExportManifest(exportDir)
+	if err != nil || manifest.Complete || len(manifest.Files) != 1 || manifest.Files[0].LastBlock != 2 {
+		t.Logf("Unexpected manifest after interruption %+v %v", manifest, err)
+		t.FailNow()
+	}
+	fetcher := func(blockNumber uint64) (*commonpb.Block, error) {
+		return blocks[blockNumber], nil
+	}
+	manifest, err = hlfsdkutil.ExportBlocks(fetcher, "settlementchannel", 0, 7, config)
+	if err != nil || !manifest.Complete || len(manifest.Files) != 3 || manifest.Files[2].FirstBlock != 6 || manifest.Files[2].BlockCount != 2 {
+		t.Logf("Unexpected manifest after resume %+v %v", manifest, err)
+		t.FailNow()
+	}
+	expected := uint64(0)
+	err = hlfsdkutil.ReadExportedBlocks(exportDir, func(block *commonpb.Block) error {
+		if block.Header.Number != expected {
+			return fmt.Errorf("Expected block %d got %d", expected, block.Header.Number)
+		}
+		expected++
+		return nil
+	})
+	if err != nil || expected != 8 {
+		t.Logf("Unexpected exported blocks %v, read %d", err, expected)
+		t.FailNow()
+	}
+}
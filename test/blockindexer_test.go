@@ -0,0 +1,95 @@
+package fabricgosdkclientcore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_BlockIndexer(t *testing.T) {
+	indexDir, _ := ioutil.TempDir("", "index")
+	defer os.RemoveAll(indexDir)
+	indexer, err := hlfsdkutil.OpenBlockIndexer(filepath.Join(indexDir, "index.db"))
+	if err != nil {
+		t.Logf("Error in opening the index %v", err)
+		t.FailNow()
+	}
+	defer indexer.Close()
+	certPEM, _ := newTestCertificate(t, "User1@distributer.net", "client")
+	start := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	blocks := [][]testTx{
+		{{txID: "tx1", mspID: "DistributerMSP", certPEM: certPEM, chaincode: "basic", args: []string{"save"}, writes: map[string]string{"KEY_1": "V1"}, eventName: "saved", timestamp: start}},
+		{{txID: "tx2", mspID: "RetailerMSP", certPEM: certPEM, chaincode: "basic", args: []string{"save"}, writes: map[string]string{"KEY_1": "V2"}, timestamp: start.Add(time.Minute)},
+			{txID: "tx3", mspID: "DistributerMSP", certPEM: certPEM, chaincode: "basic", args: []string{"delete"}, deletes: []string{"KEY_1"}, timestamp: start.Add(2 * time.Minute)},
+			{txID: "tx4", mspID: "RetailerMSP", certPEM: certPEM, chaincode: "marbles", args: []string{"read"}, writes: map[string]string{"\x00obj\x00a\x00\x00": "V"}, timestamp: start.Add(3 * time.Minute)}},
+	}
+	codes := [][]byte{{byte(pb.TxValidationCode_VALID)}, {byte(pb.TxValidationCode_VALID), byte(pb.TxValidationCode_MVCC_READ_CONFLICT), byte(pb.TxValidationCode_VALID)}}
+	for number, txs := range blocks {
+		envelopes := make([][]byte, 0)
+		for _, tx := range txs {
+			envelopes = append(envelopes, newTestEnvelope(t, tx))
+		}
+		decoded, err := hlfsdkutil.DecodeBlock(newTestBlock(uint64(number), nil, envelopes, codes[number]))
+		if err != nil {
+			t.Logf("Error in decoding block %v", err)
+			t.FailNow()
+		}
+		//Indexing twice is ignored
+		for count := 0; count < 2; count++ {
+			if err := indexer.Index(decoded); err != nil {
+				t.Logf("Error in indexing block %v", err)
+				t.FailNow()
+			}
+		}
+	}
+	if lastBlock, isFound := indexer.LastIndexedBlock(); !isFound || lastBlock != 1 {
+		t.Logf("Unexpected last indexed block %d", lastBlock)
+		t.FailNow()
+	}
+	keyTxs, err := indexer.TxsByKey("basic", "KEY_1")
+	if err != nil || len(keyTxs) != 3 || keyTxs[0].TxID != "tx1" || keyTxs[2].TxID != "tx3" {
+		t.Logf("Unexpected transactions of KEY_1 %+v %v", keyTxs, err)
+		t.FailNow()
+	}
+	//The composite key of obj with the attributes a and an empty one starts with the key of obj a
+	if compositeTxs, _ := indexer.TxsByKey("marbles", "\x00obj\x00a\x00"); len(compositeTxs) != 0 {
+		t.Logf("Transactions of a longer composite key found %+v", compositeTxs)
+		t.FailNow()
+	}
+	if compositeTxs, _ := indexer.TxsByKey("marbles", "\x00obj\x00a\x00\x00"); len(compositeTxs) != 1 || compositeTxs[0].TxID != "tx4" {
+		t.Logf("Unexpected transactions of the composite key %+v", compositeTxs)
+		t.FailNow()
+	}
+	eventTxs, _ := indexer.TxsByEvent("basic", "saved")
+	creatorTxs, _ := indexer.TxsByCreator("DistributerMSP")
+	if len(eventTxs) != 1 || len(creatorTxs) != 2 {
+		t.Logf("Unexpected transactions by event %+v or creator %+v", eventTxs, creatorTxs)
+		t.FailNow()
+	}
+	basicTxs, _ := indexer.TxsByChaincode("basic")
+	marblesTxs, _ := indexer.TxsByChaincode("marbles")
+	if len(basicTxs) != 3 || basicTxs[2].TxID != "tx3" || len(marblesTxs) != 1 || marblesTxs[0].TxID != "tx4" {
+		t.Logf("Unexpected transactions by chain code %+v %+v", basicTxs, marblesTxs)
+		t.FailNow()
+	}
+	invalidTxs, err := indexer.InvalidTxsInTimeRange(start, start.Add(time.Hour))
+	if err != nil || len(invalidTxs) != 1 || invalidTxs[0].TxID != "tx3" || invalidTxs[0].ValidationCodeName != "MVCC_READ_CONFLICT" {
+		t.Logf("Unexpected invalid transactions %+v %v", invalidTxs, err)
+		t.FailNow()
+	}
+	rangeTxs, _ := indexer.TxsInTimeRange(start.Add(time.Minute), start.Add(2*time.Minute))
+	if len(rangeTxs) != 1 || rangeTxs[0].TxID != "tx2" {
+		t.Logf("Unexpected transactions in time range %+v", rangeTxs)
+		t.FailNow()
+	}
+	allTxs, err := indexer.TxsInTimeRange(time.Time{}, start.Add(time.Hour))
+	if err != nil || len(allTxs) != 4 {
+		t.Logf("Expected every transaction from the zero time but found %+v %v", allTxs, err)
+		t.FailNow()
+	}
+}
@@ -1,42 +1,64 @@
 package fabricgosdkclientcore_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	x509 "crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
-	"fmt"
-	"io/ioutil"
-	"strings"
+	"math/big"
+	"net"
 	"testing"
+	"time"
+
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
 )
 
 func Test_AnalyzeCerts(t *testing.T) {
-	certPath := "/home/suddutt1/projects/producttracer/network/crypto-config/peerOrganizations/manuf.net/users/Admin@manuf.net/msp/admincerts/" + "Admin@manuf.net-cert.pem"
-	signatureBytes, err := ioutil.ReadFile(certPath)
-	if err == nil {
-		signatureString := string(signatureBytes)
-		fmt.Printf("Signature %s", signatureString)
-		pos := strings.Index(signatureString, "-----BEGIN CERTIFICATE-----")
-		if pos != -1 {
-			actualSignature := signatureString[pos : len(signatureString)-1]
-			fmt.Printf("Only Signature %s", actualSignature)
-			block, _ := pem.Decode([]byte(actualSignature))
-			if block != nil {
-				certificate, err := x509.ParseCertificate(block.Bytes)
-				if err != nil {
-					fmt.Printf("Error in parsing certificate %v", err)
-				} else {
-					fmt.Printf("\nCert Subject Common Name %v", certificate.Subject.CommonName)
-					fmt.Printf("\nCert Issuer Common Name %v", certificate.Issuer.CommonName)
-					fmt.Printf("\nCert Version %v\n", certificate.Version)
-					for _, extInfo := range certificate.Extensions {
-						fmt.Printf("\n%+v %+v", extInfo.Id, extInfo.Value)
-					}
-				}
-				fmt.Println("")
-			}
-		}
-
-	} else {
-		fmt.Printf("Error in GetCreator %v", err)
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Logf("Error in generating key %v", err)
+		t.FailNow()
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0x1f2e),
+		Subject:      pkix.Name{CommonName: "Admin@manuf.net", OrganizationalUnit: []string{"org1", "admin"}},
+		Issuer:       pkix.Name{CommonName: "ca.manuf.net"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		DNSNames:     []string{"peer0.manuf.net"},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1")},
+		ExtraExtensions: []pkix.Extension{{
+			Id:    asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1},
+			Value: []byte(`{"attrs":{"hf.Affiliation":"org1","hf.EnrollmentID":"Admin@manuf.net","role":"auditor"}}`),
+		}},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Logf("Error in creating certificate %v", err)
+		t.FailNow()
+	}
+	info, err := hlfsdkutil.InspectCertificate(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+	if err != nil {
+		t.Logf("Error in inspecting certificate %v", err)
+		t.FailNow()
+	}
+	if info.SubjectCommonName != "Admin@manuf.net" || info.Serial != "1f2e" || info.KeyAlgorithm != "ECDSA P-256" || info.Expired {
+		t.Logf("Unexpected certificate details %+v", info)
+		t.FailNow()
+	}
+	if info.Role != hlfsdkutil.CertRoleAdmin || len(info.DNSNames) != 1 || len(info.IPAddresses) != 1 || info.IPAddresses[0] != "10.0.0.1" {
+		t.Logf("Unexpected role or SANs %+v", info)
+		t.FailNow()
+	}
+	if info.Attributes["role"] != "auditor" || info.Attributes["hf.Affiliation"] != "org1" {
+		t.Logf("Unexpected attributes %+v", info.Attributes)
+		t.FailNow()
+	}
+	if _, err := hlfsdkutil.InspectCertificate([]byte("not a certificate")); err == nil {
+		t.Logf("Invalid PEM accepted")
+		t.FailNow()
 	}
 }
@@ -0,0 +1,39 @@
+package fabricgosdkclientcore_test
+
+import (
+	"testing"
+	"time"
+
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_CheckCertExpiry(t *testing.T) {
+	clientsMap := initializeClients(t, "Admin")
+	defer cleanup(clientsMap)
+	expiries := clientsMap["manuf"].CheckCertExpiry(hlfsdkutil.CertExpiryConfig{WarningThresholds: []time.Duration{10 * 365 * 24 * time.Hour}})
+	if len(expiries) == 0 {
+		t.Logf("No certificate checked")
+		t.FailNow()
+	}
+	for _, expiry := range expiries {
+		if len(expiry.Error) > 0 || expiry.Threshold == 0 {
+			t.Logf("Unexpected expiry %+v", expiry)
+			t.FailNow()
+		}
+		t.Logf("Certificate of %s expires in %v", expiry.UserID, expiry.TimeToExpiry)
+	}
+	alerts := make(chan *hlfsdkutil.CertExpiry, len(expiries))
+	if !clientsMap["manuf"].StartCertExpiryMonitor(hlfsdkutil.CertExpiryConfig{WarningThresholds: []time.Duration{10 * 365 * 24 * time.Hour}}, func(expiry *hlfsdkutil.CertExpiry) {
+		alerts <- expiry
+	}) {
+		t.Logf("Monitor not started")
+		t.FailNow()
+	}
+	select {
+	case <-alerts:
+	case <-time.After(10 * time.Second):
+		t.Logf("No expiry warning received")
+		t.FailNow()
+	}
+	clientsMap["manuf"].StopCertExpiryMonitor()
+}
@@ -0,0 +1,98 @@
+package fabricgosdkclientcore_test
+
+import (
+	"testing"
+
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	ordererpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/orderer"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func setTestLastConfig(t *testing.T, block *commonpb.Block, configIndex uint64) {
+	block.Metadata.Metadata[commonpb.BlockMetadataIndex_LAST_CONFIG] = mustMarshal(t, &commonpb.Metadata{Value: mustMarshal(t, &commonpb.LastConfig{Index: configIndex})})
+}
+
+func Test_ChannelConfigHistory(t *testing.T) {
+	certPEM, _ := newTestCertificate(t, "User1@manufacturer.net", "client")
+	txEnvelope := newTestEnvelope(t, testTx{txID: "tx1", mspID: "ManufacturerMSP", certPEM: certPEM, chaincode: "basic", writes: map[string]string{"KEY_1": "V1"}})
+	valid := []byte{byte(pb.TxValidationCode_VALID)}
+	blocks := []*commonpb.Block{
+		newTestBlock(0, nil, [][]byte{newTestConfigEnvelope(t, 0, []string{"ManufacturerMSP"}, map[string][]byte{"OrdererMSP": nil})}, valid),
+		newTestBlock(1, nil, [][]byte{txEnvelope}, valid),
+		newTestBlock(2, nil, [][]byte{newTestConfigEnvelope(t, 1, []string{"ManufacturerMSP", "DistributerMSP"}, map[string][]byte{"OrdererMSP": nil})}, valid),
+		newTestBlock(3, nil, [][]byte{txEnvelope}, valid),
+	}
+	for number, configIndex := range []uint64{0, 0, 2, 2} {
+		setTestLastConfig(t, blocks[number], configIndex)
+	}
+	fetcher := func(blockNumber uint64) (*commonpb.Block, error) {
+		return blocks[blockNumber], nil
+	}
+	history, err := hlfsdkutil.ChannelConfigHistory(fetcher, 3)
+	if err != nil || len(history) != 2 {
+		t.Logf("Unexpected config history %+v %v", history, err)
+		t.FailNow()
+	}
+	if history[0].BlockNumber != 0 || len(history[0].Changes) != 0 || history[1].BlockNumber != 2 || history[1].Sequence != 1 {
+		t.Logf("Unexpected config versions %+v %+v", history[0], history[1])
+		t.FailNow()
+	}
+	if len(history[1].Changes) != 1 || history[1].Changes[0] != "Application organization DistributerMSP (DistributerMSP) added" {
+		t.Logf("Unexpected changes %v", history[1].Changes)
+		t.FailNow()
+	}
+}
+
+func Test_DiffChannelConfigs(t *testing.T) {
+	batchSize := func(maxMessageCount uint32) *commonpb.ConfigValue {
+		return &commonpb.ConfigValue{Value: mustMarshal(t, &ordererpb.BatchSize{MaxMessageCount: maxMessageCount, AbsoluteMaxBytes: 99, PreferredMaxBytes: 512})}
+	}
+	anchorPeers := func(hosts ...string) *commonpb.ConfigValue {
+		peers := &pb.AnchorPeers{}
+		for _, host := range hosts {
+			peers.AnchorPeers = append(peers.AnchorPeers, &pb.AnchorPeer{Host: host, Port: 7051})
+		}
+		return &commonpb.ConfigValue{Value: mustMarshal(t, peers)}
+	}
+	newConfig := func(maxMessageCount uint32, policyType int32, anchorHosts ...string) *commonpb.Config {
+		return &commonpb.Config{ChannelGroup: &commonpb.ConfigGroup{Groups: map[string]*commonpb.ConfigGroup{
+			"Application": {Groups: map[string]*commonpb.ConfigGroup{"DistributerMSP": {Values: map[string]*commonpb.ConfigValue{"AnchorPeers": anchorPeers(anchorHosts...)}}},
+				Policies: map[string]*commonpb.ConfigPolicy{"Writers": {Policy: &commonpb.Policy{Type: policyType}}}},
+			"Orderer": {Values: map[string]*commonpb.ConfigValue{"BatchSize": batchSize(maxMessageCount)}},
+		}}}
+	}
+	if changes := hlfsdkutil.DiffChannelConfigs(newConfig(10, 3, "peer0"), newConfig(10, 3, "peer0")); len(changes) != 0 {
+		t.Logf("Expected no changes %v", changes)
+		t.FailNow()
+	}
+	changes := hlfsdkutil.DiffChannelConfigs(newConfig(10, 3, "peer0"), newConfig(20, 1, "peer0", "peer1"))
+	expected := []string{
+		"Anchor peers of DistributerMSP changed from [peer0:7051] to [peer0:7051 peer1:7051]",
+		"Policy Channel/Application/Writers changed",
+		"Batch size changed from [max message count 10, absolute max bytes 99, preferred max bytes 512] to [max message count 20, absolute max bytes 99, preferred max bytes 512]",
+	}
+	if len(changes) != len(expected) {
+		t.Logf("Unexpected changes %v", changes)
+		t.FailNow()
+	}
+	for index := range expected {
+		if changes[index] != expected[index] {
+			t.Logf("Expected %s got %s", expected[index], changes[index])
+			t.FailNow()
+		}
+	}
+}
+
+func Test_GetChannelConfigHistory(t *testing.T) {
+	clientsMap := initializeClients(t, "Admin")
+	defer cleanup(clientsMap)
+	history, err := clientsMap["dist"].GetChannelConfigHistory("settlementchannel")
+	if err != nil {
+		t.Logf("Error in reading the config history %v", err)
+		t.FailNow()
+	}
+	for _, version := range history {
+		t.Logf("Config block %d sequence %d at %v: %v", version.BlockNumber, version.Sequence, version.Timestamp, version.Changes)
+	}
+}
@@ -0,0 +1,131 @@
+package fabricgosdkclientcore_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_DeadLetterStore_Bounded(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "deadletters")
+	defer os.RemoveAll(dir)
+	store, err := hlfsdkutil.NewDeadLetterStore(dir, 3)
+	if err != nil {
+		t.Logf("Error in creating dead letter store %v", err)
+		t.FailNow()
+	}
+	for block := uint64(1); block <= 5; block++ {
+		letter := &hlfsdkutil.DeadLetter{
+			Subscription: "settlementchannel_User1_cc_CCEVENT",
+			Sink:         "hook",
+			Event:        &hlfsdkutil.SinkEvent{Type: hlfsdkutil.SinkEventTypeCC, BlockNumber: block},
+			Error:        errors.New("connection refused").Error(),
+			Attempts:     1,
+		}
+		if err := store.Add(letter); err != nil {
+			t.Logf("Error in adding dead letter %v", err)
+			t.FailNow()
+		}
+	}
+	letters, _ := store.List()
+	if len(letters) != 3 || letters[0].Event.BlockNumber != 3 {
+		t.Logf("Expected the 3 latest dead letters but found %d", len(letters))
+		t.FailNow()
+	}
+	letter, err := store.Get(letters[1].ID)
+	if err != nil || letter.Event.BlockNumber != 4 {
+		t.Logf("Error in getting dead letter %v", err)
+		t.FailNow()
+	}
+	letter.Attempts++
+	store.Update(letter)
+	if purged, _ := store.Purge(letters[0].ID); purged != 1 {
+		t.Logf("Expected 1 purged dead letter but found %d", purged)
+		t.FailNow()
+	}
+	if purged, _ := store.Purge(); purged != 2 {
+		t.Logf("Expected 2 purged dead letters but found %d", purged)
+		t.FailNow()
+	}
+}
+
+func Test_DeadLetterStore_SameTick(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "deadletters")
+	defer os.RemoveAll(dir)
+	store, _ := hlfsdkutil.NewDeadLetterStore(dir, 100)
+	for block := uint64(1); block <= 50; block++ {
+		if err := store.Add(&hlfsdkutil.DeadLetter{Sink: "hook", Event: &hlfsdkutil.SinkEvent{BlockNumber: block}}); err != nil {
+			t.Logf("Error in adding dead letter %v", err)
+			t.FailNow()
+		}
+	}
+	letters, _ := store.List()
+	if len(letters) != 50 {
+		t.Logf("Expected 50 dead letters but found %d", len(letters))
+		t.FailNow()
+	}
+	for index, letter := range letters {
+		if letter.Event.BlockNumber != uint64(index+1) {
+			t.Logf("Dead letter of block %d out of order at %d", letter.Event.BlockNumber, index)
+			t.FailNow()
+		}
+	}
+}
+
+func Test_ReplayDeadLetters(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "deadletters")
+	defer os.RemoveAll(dir)
+	isAvailable := false
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAvailable {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	store, _ := hlfsdkutil.NewDeadLetterStore(dir, 10)
+	client := new(hlfsdkutil.FabricSDKClient)
+	client.SetDeadLetterStore(store)
+	if err := client.AddEventSink(hlfsdkutil.EventSinkConfig{Name: "hook", Type: "webhook", URL: server.URL, MaxRetries: 1, RetryInterval: 10 * time.Millisecond}); err != nil {
+		t.Logf("Error in adding event sink %v", err)
+		t.FailNow()
+	}
+	for block := uint64(1); block <= 3; block++ {
+		store.Add(&hlfsdkutil.DeadLetter{Sink: "hook", Event: &hlfsdkutil.SinkEvent{Type: hlfsdkutil.SinkEventTypeBlock, BlockNumber: block}, Attempts: 1})
+	}
+	store.Add(&hlfsdkutil.DeadLetter{Sink: "removed", Event: &hlfsdkutil.SinkEvent{Type: hlfsdkutil.SinkEventTypeBlock, BlockNumber: 4}, Attempts: 1})
+	letters, _ := client.ListDeadLetters()
+	if err := client.ReplayDeadLetter(letters[0].ID); err == nil {
+		t.Logf("Replay to an unavailable sink succeeded")
+		t.FailNow()
+	}
+	//The webhook tries the replay twice with one retry
+	if letter, _ := client.GetDeadLetter(letters[0].ID); letter == nil || letter.Attempts != 3 {
+		t.Logf("Attempts of the failed replay not updated %+v", letter)
+		t.FailNow()
+	}
+	isAvailable = true
+	if err := client.ReplayDeadLetter(letters[0].ID); err != nil {
+		t.Logf("Replay failed %v", err)
+		t.FailNow()
+	}
+	replayed, err := client.ReplayDeadLetters()
+	if replayed != 2 || err == nil {
+		t.Logf("Expected 2 replays and the error of the unknown sink but found %d %v", replayed, err)
+		t.FailNow()
+	}
+	letters, _ = client.ListDeadLetters()
+	if received != 3 || len(letters) != 1 || letters[0].Sink != "removed" {
+		t.Logf("Expected 3 deliveries and the dead letter of the unknown sink but found %d %d", received, len(letters))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,108 @@
+package fabricgosdkclientcore_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_FileEventSink_Roll(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "filesink")
+	defer os.RemoveAll(dir)
+	sink, err := hlfsdkutil.NewEventSink(hlfsdkutil.EventSinkConfig{Name: "ccfile", Type: "file", Path: dir, MaxFileSize: 300, MaxFiles: 2})
+	if err != nil {
+		t.Logf("Error in creating file sink %v", err)
+		t.FailNow()
+	}
+	for block := uint64(1); block <= 10; block++ {
+		event := &hlfsdkutil.SinkEvent{Subscription: "settlementchannel_User1_cc_CCEVENT", Type: hlfsdkutil.SinkEventTypeCC, ChannelID: "settlementchannel", ChaincodeID: "cc", EventName: "saved", BlockNumber: block}
+		if err := sink.Send(event); err != nil {
+			t.Logf("Error in sending event %v", err)
+			t.FailNow()
+		}
+	}
+	sink.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "ccfile-*.jsonl"))
+	if len(files) != 2 {
+		t.Logf("Expected 2 rolled files but found %d", len(files))
+		t.FailNow()
+	}
+	current, err := os.Open(filepath.Join(dir, "ccfile.jsonl"))
+	if err != nil {
+		t.Logf("Current file missing %v", err)
+		t.FailNow()
+	}
+	defer current.Close()
+	var lastEvent hlfsdkutil.SinkEvent
+	scanner := bufio.NewScanner(current)
+	for scanner.Scan() {
+		if err := json.Unmarshal(scanner.Bytes(), &lastEvent); err != nil {
+			t.Logf("Invalid json line %v", err)
+			t.FailNow()
+		}
+	}
+	if lastEvent.BlockNumber != 10 {
+		t.Logf("Expected last block 10 but found %d", lastEvent.BlockNumber)
+		t.FailNow()
+	}
+}
+
+func Test_WebhookEventSink_SignAndRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(hlfsdkutil.WebhookSignatureHeader) != "sha256="+hlfsdkutil.SignWebhookBody([]byte("s3cret"), body) {
+			t.Logf("Invalid signature received")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	sink, _ := hlfsdkutil.NewEventSink(hlfsdkutil.EventSinkConfig{Name: "hook", Type: "webhook", URL: server.URL, Secret: "s3cret", MaxRetries: 3, RetryInterval: 10 * time.Millisecond})
+	if err := sink.Send(&hlfsdkutil.SinkEvent{Subscription: "sub", Type: hlfsdkutil.SinkEventTypeBlock, BlockNumber: 4}); err != nil {
+		t.Logf("Webhook delivery failed %v", err)
+		t.FailNow()
+	}
+	if attempts != 3 {
+		t.Logf("Expected 3 attempts but found %d", attempts)
+		t.FailNow()
+	}
+	server.Close()
+	err := sink.Send(&hlfsdkutil.SinkEvent{Subscription: "sub", Type: hlfsdkutil.SinkEventTypeBlock, BlockNumber: 5})
+	if deliveryErr, isDeliveryErr := err.(*hlfsdkutil.SinkDeliveryError); !isDeliveryErr || deliveryErr.Attempts != 4 {
+		t.Logf("Expected the error of 4 delivery attempts but found %v", err)
+		t.FailNow()
+	}
+}
+
+func Test_EventCheckpointStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "checkpoint")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoints.json")
+	store, _ := hlfsdkutil.NewEventCheckpointStore(path)
+	store.Update("sub", 5)
+	store.Update("sub", 3)
+	reloaded, err := hlfsdkutil.NewEventCheckpointStore(path)
+	if err != nil {
+		t.Logf("Error in reloading checkpoints %v", err)
+		t.FailNow()
+	}
+	if blockNumber, isFound := reloaded.Get("sub"); !isFound || blockNumber != 5 {
+		t.Logf("Expected checkpoint 5 but found %d", blockNumber)
+		t.FailNow()
+	}
+}
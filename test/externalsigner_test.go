@@ -0,0 +1,66 @@
+package fabricgosdkclientcore_test
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	mspprotos "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_ExternalSigner(t *testing.T) {
+	certPEM, privateKey := newTestCertificate(t, "remoteadmin")
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	localSigner := hlfsdkutil.NewLocalExternalSigner()
+	if err := localSigner.AddKey("admin-key", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})); err != nil {
+		t.Logf("Error in adding key %v", err)
+		t.FailNow()
+	}
+	signingService := httptest.NewServer(localSigner)
+	defer signingService.Close()
+
+	identity, err := hlfsdkutil.NewExternalSigningIdentity("remoteadmin", "ManufMSP", certPEM, hlfsdkutil.NewHTTPExternalSigner(signingService.URL, "token"), "admin-key")
+	if err != nil {
+		t.Logf("Error in creating identity %v", err)
+		t.FailNow()
+	}
+	halfOrder := new(big.Int).Rsh(privateKey.Curve.Params().N, 1)
+	message := []byte("proposal bytes")
+	//ECDSA signatures are random, half of them have a high S before normalization
+	for index := 0; index < 8; index++ {
+		signature, err := identity.Sign(message)
+		if err != nil {
+			t.Logf("Error in signing %v", err)
+			t.FailNow()
+		}
+		ecdsaSig := struct{ R, S *big.Int }{}
+		if _, err := asn1.Unmarshal(signature, &ecdsaSig); err != nil || ecdsaSig.S.Cmp(halfOrder) > 0 {
+			t.Logf("Signature is not low S %v", err)
+			t.FailNow()
+		}
+		if err := identity.Verify(message, signature); err != nil {
+			t.Logf("Signature not verified %v", err)
+			t.FailNow()
+		}
+	}
+	serialized, err := identity.Serialize()
+	creator := new(mspprotos.SerializedIdentity)
+	if err != nil || proto.Unmarshal(serialized, creator) != nil || creator.Mspid != "ManufMSP" || string(creator.IdBytes) != string(certPEM) {
+		t.Logf("Invalid serialized identity %v", err)
+		t.FailNow()
+	}
+	if _, err := identity.PrivateKey().Bytes(); err == nil {
+		t.Logf("External private key exported")
+		t.FailNow()
+	}
+	unknownKey, _ := hlfsdkutil.NewExternalSigningIdentity("remoteadmin", "ManufMSP", certPEM, hlfsdkutil.NewHTTPExternalSigner(signingService.URL, ""), "other-key")
+	if _, err := unknownKey.Sign(message); err == nil {
+		t.Logf("Signature with an unknown key")
+		t.FailNow()
+	}
+}
@@ -1,14 +1,17 @@
 package fabricgosdkclientcore_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
 )
 
 func Test_Block_EventListener(t *testing.T) {
@@ -28,6 +31,24 @@ func Test_Block_EventListener(t *testing.T) {
 	}()
 	wg.Wait()
 
+}
+func Test_DecodedBlock_EventListener(t *testing.T) {
+	osSigChan := make(chan os.Signal)
+	signal.Notify(osSigChan, os.Interrupt, syscall.SIGTERM)
+
+	clientsMap := initializeClients(t, "Admin")
+	defer cleanup(clientsMap)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	clientsMap["dist"].RegisterForDecodedBlockEvents("settlementchannel", "User1", nil, &wg, checkDecodedBlockEvents)
+	go func() {
+		<-osSigChan
+		fmt.Println("Ctrl-C detected..")
+		clientsMap["dist"].DegisterBlockevent("settlementchannel", "User1")
+
+	}()
+	wg.Wait()
+
 }
 func Test_CC_EventListener(t *testing.T) {
 	osSigChan := make(chan os.Signal)
@@ -55,12 +76,22 @@ func checkCCEvents(ccEventChan <-chan *fab.CCEvent, wg *sync.WaitGroup) {
 		select {
 		case event, ok := <-ccEventChan:
 			if !ok {
-				fmt.Printf("\nUnexpected closed channel while waiting for Tx Status event")
+				fmt.Printf("\nEvent channel closed")
+				return
 			}
 			fmt.Printf("\nReceived chaincode event: %#v", event)
 		}
 	}
 }
+func checkDecodedBlockEvents(blockChan <-chan *hlfsdkutil.DecodedBlock, wg *sync.WaitGroup) {
+	defer wg.Done()
+	fmt.Println("Started listening....")
+	for block := range blockChan {
+		for _, tx := range block.Transactions {
+			fmt.Printf("Block %d tx %s %s.%s by %s : %s\n", block.Number, tx.TxID, tx.ChaincodeName, tx.Function, tx.CreatorMSPID, tx.ValidationCodeName)
+		}
+	}
+}
 func checkBlockEvents(eventChan <-chan *fab.BlockEvent, wg *sync.WaitGroup) {
 	defer wg.Done()
 	fmt.Println("Started listening....")
@@ -68,7 +99,8 @@ func checkBlockEvents(eventChan <-chan *fab.BlockEvent, wg *sync.WaitGroup) {
 		select {
 		case event, ok := <-eventChan:
 			if !ok {
-				fmt.Printf("unexpected closed channel while waiting for Tx Status event")
+				fmt.Printf("Event channel closed")
+				return
 			}
 			//fmt.Printf("Received block event: %+v\n", event)
 			if event.Block == nil {
@@ -78,3 +110,19 @@ func checkBlockEvents(eventChan <-chan *fab.BlockEvent, wg *sync.WaitGroup) {
 		}
 	}
 }
+func Test_WaitForTx(t *testing.T) {
+	txID := os.Getenv("TX_ID")
+	if len(txID) == 0 {
+		t.Skip("TX_ID is not set")
+	}
+	clientsMap := initializeClients(t, "Admin")
+	defer cleanup(clientsMap)
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	status, err := clientsMap["dist"].WaitForTx(ctx, "settlementchannel", txID)
+	if err != nil {
+		t.Logf("Error in waiting for transaction %v", err)
+		t.FailNow()
+	}
+	t.Logf("Transaction %s committed in block %d with %s", status.TxID, status.BlockNumber, status.ValidationCodeName)
+}
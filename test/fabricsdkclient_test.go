@@ -1,6 +1,7 @@
 package fabricgosdkclientcore_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -189,9 +190,11 @@ func initializeClients(t *testing.T, adminUID string) map[string]*hlfsdkutil.Fab
 }
 
 func cleanup(clientMap map[string]*hlfsdkutil.FabricSDKClient) {
-	clientMap["retail"].Shutdown()
-	clientMap["dist"].Shutdown()
-	clientMap["manuf"].Shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	clientMap["retail"].Shutdown(ctx)
+	clientMap["dist"].Shutdown(ctx)
+	clientMap["manuf"].Shutdown(ctx)
 	fmt.Println("Cleanup completed")
 
 }
@@ -1,9 +1,12 @@
 package fabricgosdkclientcore_test
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"testing"
 	"time"
@@ -13,18 +16,150 @@ import (
 
 func Test_AdminEnrollNew(t *testing.T) {
 	sdkClient := initializeIBPClient(t, "admin", true)
-	defer sdkClient.Shutdown()
+	defer sdkClient.Shutdown(context.Background())
 }
 func Test_NormalUserEnroll(t *testing.T) {
 	sdkClient := initializeIBPClient(t, "admin", false)
-	defer sdkClient.Shutdown()
+	defer sdkClient.Shutdown(context.Background())
 	if !sdkClient.EnrollOrgUser("suddutt6", "cnp4test", "org1") {
 		t.Logf("Enrollment failed")
 	}
 }
+func Test_RegisterUserWithAttributes(t *testing.T) {
+	sdkClient := initializeIBPClient(t, "admin", false)
+	defer sdkClient.Shutdown(context.Background())
+	userID := fmt.Sprintf("user%d", time.Now().UnixNano())
+	err := sdkClient.RegisterAndEnrollUser(&hlfsdkutil.UserRegistration{
+		UserID:         userID,
+		Affiliation:    "org1",
+		MaxEnrollments: 2,
+		Attributes: []hlfsdkutil.UserAttribute{
+			{Name: "role", Value: "auditor", ECert: true},
+			{Name: "region", Value: "east"},
+		},
+	}, &hlfsdkutil.EnrollmentOptions{
+		AttributeRequests: []hlfsdkutil.AttributeRequest{{Name: "role"}, {Name: "region", Optional: true}},
+	})
+	if err != nil {
+		t.Logf("Registration failed %v", err)
+		t.FailNow()
+	}
+	identity, err := sdkClient.ExportIdentity(userID)
+	if err != nil {
+		t.Logf("Enrolled identity not found %v", err)
+		t.FailNow()
+	}
+	certInfo, err := hlfsdkutil.InspectCertificate(identity.CertPEM)
+	if err != nil {
+		t.Logf("Invalid enrollment certificate %v", err)
+		t.FailNow()
+	}
+	if certInfo.Attributes["role"] != "auditor" || certInfo.Attributes["region"] != "east" {
+		t.Logf("Requested attributes not in the certificate %v", certInfo.Attributes)
+		t.FailNow()
+	}
+}
+func Test_IdentityManagement(t *testing.T) {
+	sdkClient := initializeIBPClient(t, "admin", false)
+	defer sdkClient.Shutdown(context.Background())
+	userID := fmt.Sprintf("user%d", time.Now().UnixNano())
+	if err := sdkClient.RegisterAndEnrollUser(&hlfsdkutil.UserRegistration{UserID: userID, Affiliation: "org1"}, nil); err != nil {
+		t.Logf("Registration failed %v", err)
+		t.FailNow()
+	}
+	if err := sdkClient.ReenrollUser(userID, nil); err != nil {
+		t.Logf("Reenrollment failed %v", err)
+	}
+	identity, err := sdkClient.ModifyIdentity(&hlfsdkutil.UserRegistration{UserID: userID, Attributes: []hlfsdkutil.UserAttribute{{Name: "role", Value: "auditor"}}})
+	if err != nil {
+		t.Logf("Modification failed %v", err)
+		t.FailNow()
+	}
+	t.Logf("Modified identity %+v", identity)
+	result, err := sdkClient.RevokeUser(&hlfsdkutil.UserRevocation{UserID: userID, Reason: hlfsdkutil.RevocationReasonSuperseded, GenCRL: true})
+	if err != nil || len(result.RevokedCerts) == 0 {
+		t.Logf("Revocation failed %v", err)
+		t.FailNow()
+	}
+	identities, err := sdkClient.ListIdentities("")
+	if err != nil {
+		t.Logf("Listing failed %v", err)
+		t.FailNow()
+	}
+	t.Logf("%d identities registered", len(identities))
+	if _, err := sdkClient.RemoveIdentity(userID, false, ""); err != nil {
+		t.Logf("Removal failed %v", err)
+	}
+}
+func Test_ReenrollUser_EncryptedWallet(t *testing.T) {
+	sdkClient := initializeIBPClient(t, "admin", false)
+	defer sdkClient.Shutdown(context.Background())
+	walletDir, _ := ioutil.TempDir("", "wallet")
+	defer os.RemoveAll(walletDir)
+	wallet, err := hlfsdkutil.NewEncryptedFileWallet(walletDir, []byte("passphrase"))
+	if err != nil {
+		t.Logf("Error in creating encrypted wallet %v", err)
+		t.FailNow()
+	}
+	keyStorePath := filepath.Join("tmp", "msp", "keystore")
+	keysBefore, _ := filepath.Glob(filepath.Join(keyStorePath, "*_sk"))
+	sdkClient.SetWallet(wallet)
+	userID := fmt.Sprintf("user%d", time.Now().UnixNano())
+	if err := sdkClient.RegisterAndEnrollUser(&hlfsdkutil.UserRegistration{UserID: userID, Affiliation: "org1", MaxEnrollments: 2}, nil); err != nil {
+		t.Logf("Registration failed %v", err)
+		t.FailNow()
+	}
+	enrolled, err := wallet.Get(userID)
+	if err != nil {
+		t.Logf("Enrolled user not in the wallet %v", err)
+		t.FailNow()
+	}
+	if err := sdkClient.ReenrollUser(userID, nil); err != nil {
+		t.Logf("Reenrollment failed %v", err)
+		t.FailNow()
+	}
+	reenrolled, err := wallet.Get(userID)
+	if err != nil || string(reenrolled.CertPEM) == string(enrolled.CertPEM) {
+		t.Logf("Reenrolled certificate not in the wallet %v", err)
+		t.FailNow()
+	}
+	keysAfter, _ := filepath.Glob(filepath.Join(keyStorePath, "*_sk"))
+	if len(keysAfter) != len(keysBefore) {
+		t.Logf("Key store holds %d keys after the reenrollment, %d before", len(keysAfter), len(keysBefore))
+		t.FailNow()
+	}
+	for _, identity := range []*hlfsdkutil.StoredIdentity{enrolled, reenrolled} {
+		ski, _ := hlfsdkutil.ValidateCertKeyPair(identity.CertPEM, identity.KeyPEM)
+		if _, err := os.Stat(filepath.Join(keyStorePath, ski+"_sk")); err == nil {
+			t.Logf("Private key %s of %s left in the key store", ski, userID)
+			t.FailNow()
+		}
+	}
+}
+func Test_AffiliationManagement(t *testing.T) {
+	sdkClient := initializeIBPClient(t, "admin", false)
+	defer sdkClient.Shutdown(context.Background())
+	department := fmt.Sprintf("org1.dept%d", time.Now().UnixNano())
+	if _, err := sdkClient.AddAffiliation(department+".team1", true, ""); err != nil {
+		t.Logf("Unable to add affiliation %v", err)
+		t.FailNow()
+	}
+	tree, err := sdkClient.ListAffiliations("")
+	if err != nil || tree.Find(department) == nil || tree.Find(department+".team1") == nil {
+		t.Logf("Affiliations not found in the tree %v", err)
+		t.FailNow()
+	}
+	if _, err := sdkClient.RegisterUser(&hlfsdkutil.UserRegistration{UserID: "nobody", Affiliation: department + ".missing"}); err == nil {
+		t.Logf("Registration accepted a missing affiliation")
+		t.FailNow()
+	}
+	if _, err := sdkClient.RemoveAffiliation(department, true, ""); err != nil {
+		t.Logf("Unable to remove affiliation %v", err)
+	}
+}
 func Test_InstallCC(t *testing.T) {
 	sdkClient := initializeIBPClient(t, "admin", false)
-	defer sdkClient.Shutdown()
+	defer sdkClient.Shutdown(context.Background())
 
 	version := fmt.Sprintf("%d", time.Now().UnixNano())
 	ccPath := "github.com/suddutt1/chaincode"
@@ -43,7 +178,7 @@ func Test_InstallCC(t *testing.T) {
 }
 func Test_InstallAndUpgradeCC(t *testing.T) {
 	sdkClient := initializeIBPClient(t, "admin", false)
-	defer sdkClient.Shutdown()
+	defer sdkClient.Shutdown(context.Background())
 
 	version := fmt.Sprintf("%d", time.Now().UnixNano())
 	ccPath := "github.com/suddutt1/chaincode"
@@ -71,7 +206,7 @@ func Test_InstallAndUpgradeCC(t *testing.T) {
 }
 func Test_InvokeTrxn_Query_Loop_IBP(t *testing.T) {
 	sdkClient := initializeIBPClient(t, "admin", false)
-	defer sdkClient.Shutdown()
+	defer sdkClient.Shutdown(context.Background())
 
 	channelName := "defaultchannel"
 	osSigChan := make(chan os.Signal)
@@ -0,0 +1,69 @@
+package fabricgosdkclientcore_test
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_IdentityFiles(t *testing.T) {
+	storeDir, _ := ioutil.TempDir("", "identitystore")
+	defer os.RemoveAll(storeDir)
+	certPEM, privateKey := newTestCertificate(t, "remoteadmin")
+	keyDER, _ := x509.MarshalECPrivateKey(privateKey)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	_, otherKey := newTestCertificate(t, "other")
+	otherDER, _ := x509.MarshalECPrivateKey(otherKey)
+	if _, err := hlfsdkutil.ValidateCertKeyPair(certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: otherDER})); err == nil {
+		t.Logf("Mismatching key accepted")
+		t.FailNow()
+	}
+
+	credentialStorePath, keyStorePath := filepath.Join(storeDir, "state-store"), filepath.Join(storeDir, "msp", "keystore")
+	identity := &hlfsdkutil.StoredIdentity{UserID: "remoteadmin", MSPID: "ManufMSP", CertPEM: certPEM, KeyPEM: keyPEM}
+	if err := hlfsdkutil.WriteIdentityFiles(credentialStorePath, keyStorePath, identity); err != nil {
+		t.Logf("Error in writing identity %v", err)
+		t.FailNow()
+	}
+	ski := sha256.Sum256(elliptic.Marshal(privateKey.Curve, privateKey.X, privateKey.Y))
+	if _, err := os.Stat(filepath.Join(keyStorePath, hex.EncodeToString(ski[:])+"_sk")); err != nil {
+		t.Logf("Private key not stored under its SKI %v", err)
+		t.FailNow()
+	}
+	readIdentity, err := hlfsdkutil.ReadIdentityFiles(credentialStorePath, keyStorePath, "remoteadmin", "ManufMSP")
+	if err != nil || string(readIdentity.CertPEM) != string(certPEM) {
+		t.Logf("Error in reading identity %v", err)
+		t.FailNow()
+	}
+	if _, err := hlfsdkutil.ValidateCertKeyPair(readIdentity.CertPEM, readIdentity.KeyPEM); err != nil {
+		t.Logf("Stored key does not match %v", err)
+		t.FailNow()
+	}
+
+	ibpJSON, err := readIdentity.IBPJSON()
+	ibpEntry := make(map[string]map[string]string)
+	if err != nil || json.Unmarshal(ibpJSON, &ibpEntry) != nil || ibpEntry["cert"]["pem"] != string(certPEM) {
+		t.Logf("Invalid IBP entry %s", ibpJSON)
+		t.FailNow()
+	}
+	mspDir := filepath.Join(storeDir, "exported-msp")
+	if err := readIdentity.WriteMSPDir(mspDir); err != nil {
+		t.Logf("Error in writing MSP directory %v", err)
+		t.FailNow()
+	}
+	for _, mspFile := range []string{"signcerts/cert.pem", "admincerts/cert.pem", "keystore/" + hex.EncodeToString(ski[:]) + "_sk"} {
+		if _, err := os.Stat(filepath.Join(mspDir, mspFile)); err != nil {
+			t.Logf("Missing MSP file %v", err)
+			t.FailNow()
+		}
+	}
+}
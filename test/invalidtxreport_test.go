@@ -0,0 +1,51 @@
+package fabricgosdkclientcore_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_InvalidTxReport(t *testing.T) {
+	certPEM, _ := newTestCertificate(t, "User1@distributer.net", "client")
+	report := hlfsdkutil.NewInvalidTxReport("settlementchannel", 2)
+	codes := []pb.TxValidationCode{pb.TxValidationCode_MVCC_READ_CONFLICT, pb.TxValidationCode_VALID, pb.TxValidationCode_MVCC_READ_CONFLICT, pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE, pb.TxValidationCode_MVCC_READ_CONFLICT}
+	for number, code := range codes {
+		envelope := newTestEnvelope(t, testTx{txID: fmt.Sprintf("tx%d", number), mspID: "DistributerMSP", certPEM: certPEM, chaincode: "basic", args: []string{"save", "KEY_1"}, writes: map[string]string{"KEY_1": "V"}})
+		decoded, _ := hlfsdkutil.DecodeBlock(newTestBlock(uint64(number+10), nil, [][]byte{envelope}, []byte{byte(code)}))
+		report.Add(decoded)
+	}
+	if report.TotalTxs != 5 || report.InvalidTxs != 4 || report.FromBlock != 10 || report.ToBlock != 14 {
+		t.Logf("Unexpected totals %+v", report)
+		t.FailNow()
+	}
+	if report.ByCode["MVCC_READ_CONFLICT"] != 3 || report.ByFunction["basic.save"] != 4 || len(report.Groups) != 2 {
+		t.Logf("Unexpected counts %+v", report)
+		t.FailNow()
+	}
+	mvccGroup := report.Groups[0]
+	if mvccGroup.Count != 3 || len(mvccGroup.ExampleTxIDs) != 2 || mvccGroup.FirstBlock != 10 || mvccGroup.LastBlock != 14 {
+		t.Logf("Unexpected group %+v", mvccGroup)
+		t.FailNow()
+	}
+	jsonBuffer := new(bytes.Buffer)
+	if err := report.WriteJSON(jsonBuffer); err != nil || !json.Valid(jsonBuffer.Bytes()) {
+		t.Logf("Invalid JSON report %v", err)
+		t.FailNow()
+	}
+	csvBuffer := new(bytes.Buffer)
+	if err := report.WriteCSV(csvBuffer); err != nil {
+		t.Logf("Error in writing CSV report %v", err)
+		t.FailNow()
+	}
+	records, err := csv.NewReader(csvBuffer).ReadAll()
+	if err != nil || len(records) != 3 || records[1][0] != "MVCC_READ_CONFLICT" || records[1][4] != "3" || records[1][7] != "tx0 tx2" {
+		t.Logf("Unexpected CSV report %v %v", records, err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,59 @@
+package fabricgosdkclientcore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_KeyHistory(t *testing.T) {
+	indexDir, _ := ioutil.TempDir("", "index")
+	defer os.RemoveAll(indexDir)
+	indexer, err := hlfsdkutil.OpenBlockIndexer(filepath.Join(indexDir, "index.db"))
+	if err != nil {
+		t.Logf("Error in opening the index %v", err)
+		t.FailNow()
+	}
+	defer indexer.Close()
+	certPEM, _ := newTestCertificate(t, "User1@distributer.net", "client")
+	blocks := [][]testTx{
+		{{txID: "tx1", mspID: "DistributerMSP", certPEM: certPEM, chaincode: "basic", writes: map[string]string{"KEY_1": "V1", "KEY_10": "OTHER", "KEY_1\x00SUB": "OTHER"}}},
+		{{txID: "tx2", mspID: "RetailerMSP", certPEM: certPEM, chaincode: "basic", writes: map[string]string{"KEY_1": "V2"}},
+			{txID: "tx3", mspID: "DistributerMSP", certPEM: certPEM, chaincode: "basic", deletes: []string{"KEY_1"}}},
+		{{txID: "tx4", mspID: "RetailerMSP", certPEM: certPEM, chaincode: "basic", deletes: []string{"KEY_1"}}},
+	}
+	codes := [][]byte{{byte(pb.TxValidationCode_VALID)}, {byte(pb.TxValidationCode_VALID), byte(pb.TxValidationCode_MVCC_READ_CONFLICT)}, {byte(pb.TxValidationCode_VALID)}}
+	history := hlfsdkutil.NewKeyHistory("basic", "KEY_1")
+	for number, txs := range blocks {
+		envelopes := make([][]byte, 0)
+		for _, tx := range txs {
+			envelopes = append(envelopes, newTestEnvelope(t, tx))
+		}
+		decoded, _ := hlfsdkutil.DecodeBlock(newTestBlock(uint64(number), nil, envelopes, codes[number]))
+		history.Add(decoded)
+		if err := indexer.Index(decoded); err != nil {
+			t.Logf("Error in indexing block %v", err)
+			t.FailNow()
+		}
+	}
+	indexedHistory, err := indexer.KeyHistory("basic", "KEY_1")
+	if err != nil {
+		t.Logf("Error in reading the indexed history %v", err)
+		t.FailNow()
+	}
+	for _, keyHistory := range []*hlfsdkutil.KeyHistory{history, indexedHistory} {
+		modifications := keyHistory.Modifications
+		if len(modifications) != 3 || string(modifications[0].Value) != "V1" || modifications[1].CreatorMSPID != "RetailerMSP" {
+			t.Logf("Unexpected history %+v", modifications)
+			t.FailNow()
+		}
+		if current := keyHistory.Current(); current.TxID != "tx4" || !current.IsDelete || current.BlockNumber != 2 {
+			t.Logf("Unexpected current modification %+v", current)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package fabricgosdkclientcore_test
+
+import (
+	"testing"
+
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_DiffBlocks(t *testing.T) {
+	certPEM, _ := newTestCertificate(t, "User1@distributer.net", "client")
+	envelope := newTestEnvelope(t, testTx{txID: "tx1", mspID: "DistributerMSP", certPEM: certPEM, chaincode: "basic", writes: map[string]string{"KEY_1": "V1"}})
+	blockA := newTestBlock(3, []byte{1}, [][]byte{envelope}, []byte{byte(pb.TxValidationCode_VALID)})
+	blockB := newTestBlock(3, []byte{1}, [][]byte{envelope}, []byte{byte(pb.TxValidationCode_VALID)})
+	if diff := hlfsdkutil.DiffBlocks(blockA, blockB); !diff.Identical || diff.HashA != diff.HashB {
+		t.Logf("Expected identical blocks %+v", diff)
+		t.FailNow()
+	}
+	blockB = newTestBlock(3, []byte{2}, [][]byte{envelope}, []byte{byte(pb.TxValidationCode_MVCC_READ_CONFLICT)})
+	diff := hlfsdkutil.DiffBlocks(blockA, blockB)
+	if diff.Identical || len(diff.Differences) != 2 || diff.HashA == diff.HashB {
+		t.Logf("Expected previous hash and validation code differences %+v", diff)
+		t.FailNow()
+	}
+	t.Logf("Differences %v", diff.Differences)
+}
+
+func Test_CompareLedgers(t *testing.T) {
+	clientsMap := initializeClients(t, "Admin")
+	defer cleanup(clientsMap)
+	comparison, err := clientsMap["dist"].CompareLedgers("settlementchannel")
+	if err != nil {
+		t.Logf("Error in comparing ledgers %v", err)
+		t.FailNow()
+	}
+	for _, peerInfo := range comparison.Peers {
+		t.Logf("%s %s height %d hash %s lagging %v diverging %v %s", peerInfo.Org, peerInfo.Peer, peerInfo.Height, peerInfo.CurrentBlockHash, peerInfo.Lagging, peerInfo.Diverging, peerInfo.Error)
+	}
+	if len(comparison.Peers) > 1 && comparison.MaxHeight > 0 {
+		diff, err := clientsMap["dist"].DiffBlockAcrossPeers("settlementchannel", 0, comparison.Peers[0].Peer, comparison.Peers[1].Peer)
+		if err != nil {
+			t.Logf("Error in comparing the genesis block %v", err)
+			t.FailNow()
+		}
+		t.Logf("Genesis block identical %v %v", diff.Identical, diff.Differences)
+	}
+}
@@ -0,0 +1,43 @@
+package fabricgosdkclientcore_test
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_LedgerExplorer(t *testing.T) {
+	clientsMap := initializeClients(t, "Admin")
+	defer cleanup(clientsMap)
+	channelName := "settlementchannel"
+	ledgerInfo, err := clientsMap["dist"].GetLedgerInfo(channelName)
+	if err != nil {
+		t.Logf("Error in querying ledger info %v", err)
+		t.FailNow()
+	}
+	t.Logf("Ledger height %d current block %s", ledgerInfo.Height, ledgerInfo.CurrentBlockHash)
+	lastBlock, err := clientsMap["dist"].GetDecodedBlock(channelName, ledgerInfo.Height-1)
+	if err != nil {
+		t.Logf("Error in retriving the last block %v", err)
+		t.FailNow()
+	}
+	byHash, err := clientsMap["dist"].GetBlockByHash(channelName, lastBlock.Hash)
+	if err != nil || byHash.Number != lastBlock.Number {
+		t.Logf("Block by hash does not match %+v %v", byHash, err)
+		t.FailNow()
+	}
+	if len(lastBlock.Transactions) > 0 {
+		byTxID, err := clientsMap["dist"].GetBlockByTxID(channelName, lastBlock.Transactions[0].TxID)
+		if err != nil || byTxID.Number != lastBlock.Number {
+			t.Logf("Block by transaction id does not match %+v %v", byTxID, err)
+			t.FailNow()
+		}
+		txDetails, err := clientsMap["dist"].GetTransaction(channelName, lastBlock.Transactions[0].TxID)
+		if err != nil || txDetails.BlockNumber != lastBlock.Number {
+			t.Logf("Transaction details do not match %+v %v", txDetails, err)
+			t.FailNow()
+		}
+		t.Logf("Transaction %s by %s is %s", txDetails.TxID, txDetails.CreatorMSPID, txDetails.ValidationCodeName)
+	}
+	blockJSON, _ := json.MarshalIndent(lastBlock, "", "  ")
+	t.Logf("Last block %s", string(blockJSON))
+}
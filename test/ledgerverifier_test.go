@@ -0,0 +1,126 @@
+package fabricgosdkclientcore_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	commonpb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+//signTestBlock adds the orderer signature to the block metadata the way the orderer does
+func signTestBlock(t *testing.T, block *commonpb.Block, mspID string, certPEM []byte, privateKey *ecdsa.PrivateKey) {
+	signatureHeader := mustMarshal(t, &commonpb.SignatureHeader{Creator: mustMarshal(t, &mspprotos.SerializedIdentity{Mspid: mspID, IdBytes: certPEM}), Nonce: []byte("nonce")})
+	headerHash := sha256.Sum256(mustMarshal(t, block.Header))
+	metadataValue := headerHash[:]
+	signedBytes := append(append(append([]byte{}, metadataValue...), signatureHeader...), hlfsdkutil.BlockHeaderBytes(block.Header)...)
+	digest := sha256.Sum256(signedBytes)
+	signature, err := privateKey.Sign(rand.Reader, digest[:], nil)
+	if err != nil {
+		t.Logf("Error in signing block %v", err)
+		t.FailNow()
+	}
+	block.Metadata.Metadata[commonpb.BlockMetadataIndex_SIGNATURES] = mustMarshal(t, &commonpb.Metadata{
+		Value:      metadataValue,
+		Signatures: []*commonpb.MetadataSignature{{SignatureHeader: signatureHeader, Signature: signature}},
+	})
+}
+
+//newTestSignedChain creates a genesis config block followed by blocks signed by the orderer
+func newTestSignedChain(t *testing.T, blockCount int, signerKey *ecdsa.PrivateKey, signerCert []byte, ordererRoot []byte) []*commonpb.Block {
+	genesis := newTestBlock(0, nil, [][]byte{newTestConfigEnvelope(t, 0, []string{"ManufacturerMSP"}, map[string][]byte{"OrdererMSP": ordererRoot})}, []byte{byte(pb.TxValidationCode_VALID)})
+	blocks := []*commonpb.Block{genesis}
+	clientCert, _ := newTestCertificate(t, "User1@manufacturer.net", "client")
+	for number := 1; number < blockCount; number++ {
+		envelope := newTestEnvelope(t, testTx{txID: fmt.Sprintf("tx%d", number), mspID: "ManufacturerMSP", certPEM: clientCert, chaincode: "basic", args: []string{"save", "KEY_1"}, writes: map[string]string{"KEY_1": "VALUE"}})
+		block := newTestBlock(uint64(number), hlfsdkutil.BlockHeaderHash(blocks[number-1].Header), [][]byte{envelope}, []byte{byte(pb.TxValidationCode_VALID)})
+		signTestBlock(t, block, "OrdererMSP", signerCert, signerKey)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func verifyTestBlocks(blocks []*commonpb.Block) *hlfsdkutil.LedgerVerification {
+	fetcher := func(blockNumber uint64) (*commonpb.Block, error) {
+		return blocks[blockNumber], nil
+	}
+	result, _ := hlfsdkutil.VerifyBlocks(fetcher, 0, uint64(len(blocks)-1), 2)
+	return result
+}
+
+func Test_LedgerVerifier(t *testing.T) {
+	ordererCert, ordererKey := newTestCertificate(t, "orderer.example.com", "orderer")
+	blocks := newTestSignedChain(t, 5, ordererKey, ordererCert, ordererCert)
+	result := verifyTestBlocks(blocks)
+	if !result.Valid || result.BlocksVerified != 5 {
+		t.Logf("Expected a valid ledger %+v %+v", result, result.FirstBroken)
+		t.FailNow()
+	}
+
+	blocks[3].Data.Data[0] = append([]byte{}, blocks[2].Data.Data[0]...)
+	result = verifyTestBlocks(blocks)
+	if result.Valid || result.FirstBroken.BlockNumber != 3 || result.BlocksVerified != 3 {
+		t.Logf("Expected a broken data hash at block 3 %+v %+v", result, result.FirstBroken)
+		t.FailNow()
+	}
+
+	otherCert, otherKey := newTestCertificate(t, "rogue.example.com", "orderer")
+	blocks = newTestSignedChain(t, 4, otherKey, otherCert, ordererCert)
+	result = verifyTestBlocks(blocks)
+	if result.Valid || result.FirstBroken.BlockNumber != 1 {
+		t.Logf("Expected a signature not issued by the orderer MSP %+v %+v", result, result.FirstBroken)
+		t.FailNow()
+	}
+}
+
+func Test_LedgerVerifier_FromConfigBlock(t *testing.T) {
+	ordererCert, ordererKey := newTestCertificate(t, "orderer.example.com", "orderer")
+	newOrdererCert, newOrdererKey := newTestCertificate(t, "orderer2.example.com", "orderer")
+	blocks := newTestSignedChain(t, 3, ordererKey, ordererCert, ordererCert)
+	setTestLastConfig(t, blocks[2], 0)
+	//Block 3 replaces the orderer CA and is signed under the previous config
+	configBlock := newTestBlock(3, hlfsdkutil.BlockHeaderHash(blocks[2].Header), [][]byte{newTestConfigEnvelope(t, 1, []string{"ManufacturerMSP"}, map[string][]byte{"OrdererMSP": newOrdererCert})}, []byte{byte(pb.TxValidationCode_VALID)})
+	signTestBlock(t, configBlock, "OrdererMSP", ordererCert, ordererKey)
+	setTestLastConfig(t, configBlock, 3)
+	clientCert, _ := newTestCertificate(t, "User1@manufacturer.net", "client")
+	envelope := newTestEnvelope(t, testTx{txID: "tx4", mspID: "ManufacturerMSP", certPEM: clientCert, chaincode: "basic", args: []string{"save", "KEY_1"}, writes: map[string]string{"KEY_1": "VALUE"}})
+	block := newTestBlock(4, hlfsdkutil.BlockHeaderHash(configBlock.Header), [][]byte{envelope}, []byte{byte(pb.TxValidationCode_VALID)})
+	signTestBlock(t, block, "OrdererMSP", newOrdererCert, newOrdererKey)
+	setTestLastConfig(t, block, 3)
+	blocks = append(blocks, configBlock, block)
+	fetcher := func(blockNumber uint64) (*commonpb.Block, error) {
+		return blocks[blockNumber], nil
+	}
+	result, err := hlfsdkutil.VerifyBlocks(fetcher, 3, 4, 2)
+	if err != nil || !result.Valid || result.BlocksVerified != 2 {
+		t.Logf("Expected valid blocks from the config block %+v %v", result, err)
+		t.FailNow()
+	}
+}
+
+func Test_VerifyExport(t *testing.T) {
+	exportDir, _ := ioutil.TempDir("", "export")
+	defer os.RemoveAll(exportDir)
+	ordererCert, ordererKey := newTestCertificate(t, "orderer.example.com", "orderer")
+	blocks := newTestSignedChain(t, 6, ordererKey, ordererCert, ordererCert)
+	fetcher := func(blockNumber uint64) (*commonpb.Block, error) {
+		return blocks[blockNumber], nil
+	}
+	config := hlfsdkutil.BlockExportConfig{Dir: exportDir, Format: hlfsdkutil.ExportFormatProtobuf, BlocksPerFile: 4}
+	if _, err := hlfsdkutil.ExportBlocks(fetcher, "settlementchannel", 0, 5, config); err != nil {
+		t.Logf("Error in exporting blocks %v", err)
+		t.FailNow()
+	}
+	result, err := hlfsdkutil.VerifyExport(exportDir, nil)
+	if err != nil || !result.Valid || result.BlocksVerified != 6 {
+		t.Logf("Expected a valid export %+v %v", result, err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,76 @@
+package fabricgosdkclientcore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_Shutdown_RejectsNewWork(t *testing.T) {
+	client := new(hlfsdkutil.FabricSDKClient)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Shutdown(ctx); err != nil {
+		t.Logf("Error in shutdown %v", err)
+		t.FailNow()
+	}
+	if _, isSuccess, err := client.Query("settlementchannel", "User1", "basic", "retrieve", nil, nil, nil); isSuccess || err != hlfsdkutil.ErrClientShutdown {
+		t.Logf("Expected query to be rejected but got %v", err)
+		t.FailNow()
+	}
+	if _, err := client.WaitForTx(ctx, "settlementchannel", "tx1"); err != hlfsdkutil.ErrClientShutdown {
+		t.Logf("Expected wait for transaction to be rejected but got %v", err)
+		t.FailNow()
+	}
+	if client.RegisterForBlockEvents("settlementchannel", "User1", nil, nil, nil) {
+		t.Logf("Expected block event registration to be rejected")
+		t.FailNow()
+	}
+	if err := client.Shutdown(ctx); err != nil {
+		t.Logf("Second shutdown failed %v", err)
+		t.FailNow()
+	}
+}
+
+func Test_Shutdown_ListenerWaitGroup(t *testing.T) {
+	clientsMap := initializeClients(t, "Admin")
+	defer cleanup(clientsMap)
+	var wgListener sync.WaitGroup
+	wgListener.Add(2)
+	blockListener := func(eventChan <-chan *fab.BlockEvent, wg *sync.WaitGroup) {
+		defer wg.Done()
+		for range eventChan {
+		}
+	}
+	ccListener := func(ccEventChan <-chan *fab.CCEvent, wg *sync.WaitGroup) {
+		defer wg.Done()
+		for range ccEventChan {
+		}
+	}
+	if !clientsMap["dist"].RegisterForBlockEvents("settlementchannel", "User1", nil, &wgListener, blockListener) ||
+		!clientsMap["dist"].RegisterForCCEvent("settlementchannel", "User1", "basic", nil, &wgListener, ccListener) {
+		t.Logf("Event registration failed")
+		t.FailNow()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := clientsMap["dist"].Shutdown(ctx); err != nil {
+		t.Logf("Error in shutdown %v", err)
+		t.FailNow()
+	}
+	listenersDone := make(chan struct{})
+	go func() {
+		wgListener.Wait()
+		close(listenersDone)
+	}()
+	select {
+	case <-listenersDone:
+	case <-time.After(10 * time.Second):
+		t.Logf("Listeners did not return after shutdown")
+		t.FailNow()
+	}
+}
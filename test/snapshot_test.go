@@ -0,0 +1,118 @@
+package fabricgosdkclientcore_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+//newTestPagedQuery serves the world state in pages the way a paginated range query does
+func newTestPagedQuery(t *testing.T, state map[string]string) func(args [][]byte) ([]byte, error) {
+	keys := make([]string, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return func(args [][]byte) ([]byte, error) {
+		pageSize, _ := strconv.Atoi(string(args[len(args)-2]))
+		start := sort.SearchStrings(keys, string(args[len(args)-1]))
+		page := hlfsdkutil.SnapshotPage{Records: make([]*hlfsdkutil.SnapshotRecord, 0)}
+		for index := start; index < len(keys) && index < start+pageSize; index++ {
+			page.Records = append(page.Records, &hlfsdkutil.SnapshotRecord{Key: keys[index], Value: json.RawMessage(state[keys[index]])})
+		}
+		if start+pageSize < len(keys) {
+			page.Bookmark = keys[start+pageSize]
+		}
+		return json.Marshal(page)
+	}
+}
+
+func Test_Snapshot_CollectAndDiff(t *testing.T) {
+	snapshotDir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(snapshotDir)
+	state := make(map[string]string)
+	for index := 0; index < 25; index++ {
+		state[fmt.Sprintf("KEY_%02d", index)] = fmt.Sprintf(`{"owner": "org%d"}`, index)
+	}
+	config := hlfsdkutil.SnapshotConfig{Function: "queryAll", PageSize: 10}
+	oldSnapshot := &hlfsdkutil.Snapshot{Channel: "settlementchannel", ChaincodeID: "basic"}
+	if err := hlfsdkutil.CollectSnapshot(oldSnapshot, newTestPagedQuery(t, state), config); err != nil || oldSnapshot.RecordCount != 25 {
+		t.Logf("Unexpected snapshot of %d records %v", oldSnapshot.RecordCount, err)
+		t.FailNow()
+	}
+	snapshotPath := filepath.Join(snapshotDir, "old.jsonl")
+	if err := hlfsdkutil.WriteSnapshot(snapshotPath, oldSnapshot); err != nil {
+		t.Logf("Error in writing snapshot %v", err)
+		t.FailNow()
+	}
+	readSnapshot, err := hlfsdkutil.ReadSnapshot(snapshotPath)
+	if err != nil || len(readSnapshot.Records) != 25 {
+		t.Logf("Error in reading snapshot %v", err)
+		t.FailNow()
+	}
+
+	delete(state, "KEY_03")
+	state["KEY_07"] = `{"owner": "retailer"}`
+	state["KEY_99"] = `{"owner": "new"}`
+	newSnapshot := &hlfsdkutil.Snapshot{Channel: "settlementchannel", ChaincodeID: "basic"}
+	if err := hlfsdkutil.CollectSnapshot(newSnapshot, newTestPagedQuery(t, state), config); err != nil {
+		t.Logf("Error in collecting snapshot %v", err)
+		t.FailNow()
+	}
+	diff := hlfsdkutil.DiffSnapshots(readSnapshot, newSnapshot)
+	if len(diff.Added) != 1 || diff.Added[0].Key != "KEY_99" || len(diff.Removed) != 1 || diff.Removed[0].Key != "KEY_03" {
+		t.Logf("Unexpected added or removed keys %+v", diff)
+		t.FailNow()
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Key != "KEY_07" {
+		t.Logf("Unexpected changed keys %+v", diff.Changed)
+		t.FailNow()
+	}
+}
+
+func Test_Snapshot_NonJSONValues(t *testing.T) {
+	snapshotDir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(snapshotDir)
+	//The chain code returns the keys and values as plain text lines
+	decodePage := func(payload []byte) (*hlfsdkutil.SnapshotPage, error) {
+		page := &hlfsdkutil.SnapshotPage{}
+		for _, line := range strings.Split(string(payload), "\n") {
+			parts := strings.SplitN(line, "=", 2)
+			page.Records = append(page.Records, &hlfsdkutil.SnapshotRecord{Key: parts[0], Value: json.RawMessage(parts[1])})
+		}
+		return page, nil
+	}
+	config := hlfsdkutil.SnapshotConfig{Function: "queryAll", DecodePage: decodePage}
+	oldSnapshot := &hlfsdkutil.Snapshot{Channel: "settlementchannel", ChaincodeID: "basic"}
+	hlfsdkutil.CollectSnapshot(oldSnapshot, func(args [][]byte) ([]byte, error) {
+		return []byte("KEY_1=owner org1\nKEY_2={\"owner\": \"org2\"}"), nil
+	}, config)
+	snapshotPath := filepath.Join(snapshotDir, "old.jsonl")
+	if err := hlfsdkutil.WriteSnapshot(snapshotPath, oldSnapshot); err != nil {
+		t.Logf("Error in writing a snapshot of plain text values %v", err)
+		t.FailNow()
+	}
+	readSnapshot, err := hlfsdkutil.ReadSnapshot(snapshotPath)
+	if err != nil || string(readSnapshot.Records[0].Value) != `"owner org1"` || string(readSnapshot.Records[1].Value) != `{"owner":"org2"}` {
+		t.Logf("Unexpected values read back %+v %v", readSnapshot, err)
+		t.FailNow()
+	}
+	newSnapshot := &hlfsdkutil.Snapshot{Records: []*hlfsdkutil.SnapshotRecord{{Key: "KEY_1", Value: json.RawMessage("owner retailer")}, {Key: "KEY_2", Value: json.RawMessage(`{"owner":"org2"}`)}}}
+	diff := hlfsdkutil.DiffSnapshots(readSnapshot, newSnapshot)
+	if len(diff.Changed) != 1 || string(diff.Changed[0].NewValue) != `"owner retailer"` {
+		t.Logf("Unexpected changed keys %+v", diff.Changed)
+		t.FailNow()
+	}
+	if _, err := json.Marshal(diff); err != nil {
+		t.Logf("Error in encoding the diff %v", err)
+		t.FailNow()
+	}
+}
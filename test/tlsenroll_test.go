@@ -0,0 +1,40 @@
+package fabricgosdkclientcore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func Test_EnrollTLS(t *testing.T) {
+	clientsMap := initializeClients(t, "Admin")
+	defer cleanup(clientsMap)
+	tlsDir, err := ioutil.TempDir("", "tlsenroll")
+	if err != nil {
+		t.Logf("Unable to create the TLS directory %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(tlsDir)
+	enrollment := hlfsdkutil.TLSEnrollment{CertPath: filepath.Join(tlsDir, "cert.pem"), KeyPath: filepath.Join(tlsDir, "key.pem")}
+	if err := clientsMap["manuf"].EnrollTLS(enrollment); err != nil {
+		t.Logf("TLS enrollment failed %v", err)
+		t.FailNow()
+	}
+	certPEM, err := ioutil.ReadFile(enrollment.CertPath)
+	if err != nil {
+		t.Logf("TLS certificate not written %v", err)
+		t.FailNow()
+	}
+	keyPEM, _ := ioutil.ReadFile(enrollment.KeyPath)
+	if _, err := hlfsdkutil.ValidateCertKeyPair(certPEM, keyPEM); err != nil {
+		t.Logf("Invalid TLS certificate and key %v", err)
+		t.FailNow()
+	}
+	if _, err := clientsMap["manuf"].InspectUserCertificate("Admin"); err != nil {
+		t.Logf("Enrollment certificate of Admin not kept %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,82 @@
+package fabricgosdkclientcore_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	hlfsdkutil "github.com/suddutt1/fabricgosdkclientcore"
+)
+
+func newTestStoredIdentity(t *testing.T, userID string) *hlfsdkutil.StoredIdentity {
+	certPEM, privateKey := newTestCertificate(t, userID)
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	return &hlfsdkutil.StoredIdentity{UserID: userID, MSPID: "ManufMSP", CertPEM: certPEM, KeyPEM: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})}
+}
+
+func testWallet(t *testing.T, wallet hlfsdkutil.Wallet) {
+	for _, userID := range []string{"user2", "user1"} {
+		if err := wallet.Put(newTestStoredIdentity(t, userID)); err != nil {
+			t.Logf("Error in storing %s %v", userID, err)
+			t.FailNow()
+		}
+	}
+	userIDs, err := wallet.List()
+	if err != nil || strings.Join(userIDs, ",") != "user1,user2" {
+		t.Logf("Unexpected identities %v %v", userIDs, err)
+		t.FailNow()
+	}
+	identity, err := wallet.Get("user1")
+	if err != nil || identity.MSPID != "ManufMSP" {
+		t.Logf("Error in reading user1 %v", err)
+		t.FailNow()
+	}
+	if _, err := hlfsdkutil.ValidateCertKeyPair(identity.CertPEM, identity.KeyPEM); err != nil {
+		t.Logf("Invalid identity read %v", err)
+		t.FailNow()
+	}
+	if err := wallet.Remove("user1"); err != nil {
+		t.Logf("Error in removing user1 %v", err)
+		t.FailNow()
+	}
+	if _, err := wallet.Get("user1"); err != hlfsdkutil.ErrIdentityNotFound {
+		t.Logf("Removed identity still found %v", err)
+		t.FailNow()
+	}
+}
+
+func Test_Wallets(t *testing.T) {
+	walletDir, _ := ioutil.TempDir("", "wallet")
+	defer os.RemoveAll(walletDir)
+	testWallet(t, hlfsdkutil.NewInMemoryWallet())
+	testWallet(t, hlfsdkutil.NewFileWallet(filepath.Join(walletDir, "state-store"), filepath.Join(walletDir, "msp", "keystore")))
+
+	encryptedDir := filepath.Join(walletDir, "encrypted")
+	wallet, err := hlfsdkutil.NewEncryptedFileWallet(encryptedDir, []byte("passphrase"))
+	if err != nil {
+		t.Logf("Error in creating encrypted wallet %v", err)
+		t.FailNow()
+	}
+	testWallet(t, wallet)
+	files, _ := filepath.Glob(filepath.Join(encryptedDir, "*.id"))
+	for _, file := range files {
+		content, _ := ioutil.ReadFile(file)
+		if strings.Contains(string(content), "PRIVATE KEY") {
+			t.Logf("Private key in clear text in %s", file)
+			t.FailNow()
+		}
+	}
+	wrongWallet, err := hlfsdkutil.NewEncryptedFileWallet(encryptedDir, []byte("wrong"))
+	if err != nil {
+		t.Logf("Error in opening encrypted wallet %v", err)
+		t.FailNow()
+	}
+	if _, err := wrongWallet.Get("user2"); err == nil {
+		t.Logf("Identity decrypted with a wrong passphrase")
+		t.FailNow()
+	}
+}
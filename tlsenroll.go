@@ -0,0 +1,266 @@
+package fabricgosdkclientcore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	channel "github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	mspclient "github.com/hyperledger/fabric-sdk-go/pkg/client/msp"
+	context "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+	core "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/core"
+	fabsdk "github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+)
+
+//Configuration keys of the client certificate used for mutual TLS
+const (
+	tlsClientCertPathKey = "client.tlsCerts.client.cert.path"
+	tlsClientKeyPathKey  = "client.tlsCerts.client.key.path"
+)
+
+//DefaultTLSProfile is the enrollment profile of TLS certificates on a CA serving both
+const DefaultTLSProfile = "tls"
+
+//TLSEnrollment enrolls a TLS client certificate. With CAName the TLS CA instance of the
+//organization is used, otherwise the default CA with Profile, tls by default. UserID and Secret
+//default to the org admin enrolled from the configuration. The certificate and key are written
+//to CertPath and KeyPath, by default in the tls directory of the crypto store. Set as
+//x-tlsEnrollment, the org admin gets its TLS client certificate when it is enrolled.
+type TLSEnrollment struct {
+	UserID   string
+	Secret   string
+	CAName   string
+	Profile  string
+	CertPath string
+	KeyPath  string
+}
+
+//overlayConfigBackend overrides values of the configuration by their dotted keys. A lookup of a
+//parent key returns the parent of the other backends with the values replaced.
+type overlayConfigBackend struct {
+	values map[string]interface{}
+	base   core.ConfigProvider
+}
+
+func (ocb *overlayConfigBackend) Lookup(key string) (interface{}, bool) {
+	if value, isFound := ocb.values[key]; isFound {
+		return value, true
+	}
+	var overridden map[string]interface{}
+	for overrideKey, value := range ocb.values {
+		if !strings.HasPrefix(strings.ToLower(overrideKey), strings.ToLower(key)+".") {
+			continue
+		}
+		if overridden == nil {
+			baseValue, _ := ocb.baseLookup(key)
+			overridden = copyConfigMap(baseValue)
+		}
+		setConfigPath(overridden, strings.Split(overrideKey[len(key)+1:], "."), value)
+	}
+	if overridden == nil {
+		return nil, false
+	}
+	return overridden, true
+}
+
+func (ocb *overlayConfigBackend) baseLookup(key string) (interface{}, bool) {
+	backends, err := ocb.base()
+	if err != nil {
+		return nil, false
+	}
+	for _, backend := range backends {
+		if value, isFound := backend.Lookup(key); isFound {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+//copyConfigMap copies the maps of a configuration value, the other values are shared
+func copyConfigMap(value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{})
+	configMap, _ := value.(map[string]interface{})
+	for key, childValue := range configMap {
+		if childMap, isMap := childValue.(map[string]interface{}); isMap {
+			copied[key] = copyConfigMap(childMap)
+		} else {
+			copied[key] = childValue
+		}
+	}
+	return copied
+}
+
+func setConfigPath(configMap map[string]interface{}, path []string, value interface{}) {
+	//The configuration keys are case insensitive, an existing key keeps its case
+	key := path[0]
+	for existingKey := range configMap {
+		if strings.EqualFold(existingKey, key) {
+			key = existingKey
+			break
+		}
+	}
+	if len(path) == 1 {
+		configMap[key] = value
+		return
+	}
+	child, isMap := configMap[key].(map[string]interface{})
+	if !isMap {
+		child = make(map[string]interface{})
+		configMap[key] = child
+	}
+	setConfigPath(child, path[1:], value)
+}
+
+//withConfigOverlay returns a configuration provider with the values set over the base provider
+func withConfigOverlay(base core.ConfigProvider, values map[string]interface{}) core.ConfigProvider {
+	overlay := &overlayConfigBackend{values: values, base: base}
+	return func() ([]core.ConfigBackend, error) {
+		backends, err := base()
+		if err != nil {
+			return nil, err
+		}
+		return append([]core.ConfigBackend{overlay}, backends...), nil
+	}
+}
+
+//Configuration keys of the identity stores, overridden for the TLS enrollment
+const (
+	credentialStorePathKey = "client.credentialStore.path"
+	cryptoStorePathKey     = "client.credentialStore.cryptoStore.path"
+)
+
+//EnrollTLS enrolls a TLS client certificate and rebuilds the SDK to use it for mutual TLS with
+//the peers and orderers. The enrollment certificate of the user is kept. The cached channel
+//clients are dropped, EnrollTLS waits for the running requests and fails while event
+//registrations are running.
+func (fsc *FabricSDKClient) EnrollTLS(enrollment TLSEnrollment) error {
+	if registrationCount := fsc.eventRegistrationCount(); registrationCount > 0 {
+		return fmt.Errorf("TLS enrollment with %d event registrations running", registrationCount)
+	}
+	if len(enrollment.UserID) == 0 {
+		enrollment.UserID, enrollment.Secret = fsc.orgAdmin, fsc.orgAdminSecret
+	}
+	if len(enrollment.CertPath) == 0 || len(enrollment.KeyPath) == 0 {
+		_, keyStorePath, err := fsc.identityStorePaths()
+		if err != nil {
+			return err
+		}
+		tlsDir := filepath.Join(filepath.Dir(keyStorePath), "tls")
+		enrollment.CertPath = filepath.Join(tlsDir, enrollment.UserID+"-cert.pem")
+		enrollment.KeyPath = filepath.Join(tlsDir, enrollment.UserID+"-key.pem")
+	}
+	tlsIdentity, err := fsc.enrollTLSIdentity(enrollment)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(enrollment.CertPath, tlsIdentity.CertPEM); err != nil {
+		return fmt.Errorf("Unable to write %s: %v", enrollment.CertPath, err)
+	}
+	if err := writeFileAtomicMode(enrollment.KeyPath, tlsIdentity.KeyPEM, 0600); err != nil {
+		return fmt.Errorf("Unable to write %s: %v", enrollment.KeyPath, err)
+	}
+	configProvider := withConfigOverlay(fsc.getConfigProvider(), map[string]interface{}{
+		tlsClientCertPathKey: enrollment.CertPath,
+		tlsClientKeyPathKey:  enrollment.KeyPath,
+	})
+	if err := fsc.reloadSDK(configProvider); err != nil {
+		return err
+	}
+	_logger.Infof("TLS client certificate of %s written to %s", enrollment.UserID, enrollment.CertPath)
+	return nil
+}
+
+//eventRegistrationCount returns the number of running event registrations
+func (fsc *FabricSDKClient) eventRegistrationCount() int {
+	fsc.eventSubsLock.Lock()
+	defer fsc.eventSubsLock.Unlock()
+	return len(fsc.eventSubsReg)
+}
+
+//enrollTLSIdentity enrolls with the TLS CA or profile through an SDK of its own, with identity
+//stores in a temporary directory, so the stores of the client keep the enrollment certificate
+func (fsc *FabricSDKClient) enrollTLSIdentity(enrollment TLSEnrollment) (*StoredIdentity, error) {
+	storeDir, err := ioutil.TempDir("", "tlsenroll")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create the TLS enrollment store: %v", err)
+	}
+	defer os.RemoveAll(storeDir)
+	configProvider := withConfigOverlay(fsc.getConfigProvider(), map[string]interface{}{
+		credentialStorePathKey: filepath.Join(storeDir, "msp"),
+		cryptoStorePathKey:     filepath.Join(storeDir, "keystore"),
+	})
+	sdk, err := fabsdk.New(configProvider, fabsdk.WithCorePkg(newExternalSignerProviderFactory()))
+	if err != nil {
+		return nil, fmt.Errorf("Error in initialization of the TLS enrollment SDK %v", err)
+	}
+	defer sdk.Close()
+	clientOptions := []mspclient.ClientOption{mspclient.WithOrg(fsc.clientOrg)}
+	enrollOptions := []mspclient.EnrollmentOption{mspclient.WithSecret(enrollment.Secret)}
+	if len(enrollment.CAName) > 0 {
+		clientOptions = append(clientOptions, mspclient.WithCAInstance(enrollment.CAName))
+		if len(enrollment.Profile) > 0 {
+			enrollOptions = append(enrollOptions, mspclient.WithProfile(enrollment.Profile))
+		}
+	} else {
+		profile := enrollment.Profile
+		if len(profile) == 0 {
+			profile = DefaultTLSProfile
+		}
+		enrollOptions = append(enrollOptions, mspclient.WithProfile(profile))
+	}
+	mspClient, err := mspclient.New(sdk.Context(), clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create the client of the TLS CA %s: %v", enrollment.CAName, err)
+	}
+	if err := mspClient.Enroll(enrollment.UserID, enrollOptions...); err != nil {
+		return nil, fmt.Errorf("TLS enrollment of %s failed: %v", enrollment.UserID, err)
+	}
+	signingIdentity, err := mspClient.GetSigningIdentity(enrollment.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the TLS identity of %s: %v", enrollment.UserID, err)
+	}
+	credentialStorePath, keyStorePath, err := sdkStorePaths(sdk, configProvider)
+	if err != nil {
+		return nil, err
+	}
+	return ReadIdentityFiles(credentialStorePath, keyStorePath, enrollment.UserID, signingIdentity.Identifier().MSPID)
+}
+
+//reloadSDK replaces the SDK with one created from the configuration provider. New requests wait
+//until the running ones are done and the SDK is replaced.
+func (fsc *FabricSDKClient) reloadSDK(configProvider core.ConfigProvider) error {
+	sdk, err := fabsdk.New(configProvider, fabsdk.WithCorePkg(newExternalSignerProviderFactory()))
+	if err != nil {
+		return fmt.Errorf("Error in initialization of SDK %v", err)
+	}
+	mspClient, err := mspclient.New(sdk.Context())
+	if err != nil {
+		sdk.Close()
+		return fmt.Errorf("Unable to create MSPClient %v", err)
+	}
+	//beginWork takes lifecycleLock, so no request starts while the running ones are drained
+	fsc.lifecycleLock.Lock()
+	defer fsc.lifecycleLock.Unlock()
+	if fsc.isShutdown {
+		sdk.Close()
+		return ErrClientShutdown
+	}
+	fsc.inFlight.Wait()
+	if registrationCount := fsc.eventRegistrationCount(); registrationCount > 0 {
+		sdk.Close()
+		return fmt.Errorf("TLS enrollment with %d event registrations running", registrationCount)
+	}
+	fsc.clientsLock.Lock()
+	previousSDK := fsc.sdk
+	fsc.sdk = sdk
+	fsc.configProvider = configProvider
+	fsc.orgMSPClient = mspClient
+	fsc.channelContextProviderMap = make(map[string]context.ChannelProvider)
+	fsc.channelContextMap = make(map[string]context.Channel)
+	fsc.channelClientMap = make(map[string]*channel.Client)
+	fsc.clientsLock.Unlock()
+	previousSDK.Close()
+	return nil
+}
@@ -0,0 +1,92 @@
+package fabricgosdkclientcore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	eventClient "github.com/hyperledger/fabric-sdk-go/pkg/fab/events/client"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+)
+
+//TxStatus is the commit status of a transaction
+type TxStatus struct {
+	TxID               string `json:"txId"`
+	ValidationCode     int32  `json:"validationCode"`
+	ValidationCodeName string `json:"validationCodeName"`
+	BlockNumber        uint64 `json:"blockNumber"`
+}
+
+//IsValid returns true if the transaction was committed as valid
+func (txs *TxStatus) IsValid() bool {
+	return txs.ValidationCode == int32(pb.TxValidationCode_VALID)
+}
+
+//ValidationCodeName returns the name of a transaction validation code
+func ValidationCodeName(code int32) string {
+	if name, isFound := pb.TxValidationCode_name[code]; isFound {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN_%d", code)
+}
+
+func newTxStatus(txID string, code int32, blockNumber uint64) *TxStatus {
+	return &TxStatus{TxID: txID, ValidationCode: code, ValidationCodeName: ValidationCodeName(code), BlockNumber: blockNumber}
+}
+
+//WaitForTx waits until the transaction is committed in the channel and returns its validation
+//code and block number. The channel event service is subscribed before the ledger is looked up,
+//so a transaction committed before the call is found in the ledger and one committed afterwards
+//is reported by its status event. The wait ends with an error when ctx is done. Shutdown waits
+//for the running waits like for the other requests.
+func (fsc *FabricSDKClient) WaitForTx(ctx context.Context, channelID, txID string) (*TxStatus, error) {
+	if err := fsc.beginWork(); err != nil {
+		return nil, err
+	}
+	defer fsc.endWork()
+	channelContext, _, isFound := fsc.getChannelContext(channelID, fsc.orgAdmin)
+	if !isFound {
+		return nil, fmt.Errorf("Channel cound not be found for %s channelname and user %s", channelID, fsc.orgAdmin)
+	}
+	eventService, err := channelContext.ChannelService().EventService(eventClient.WithBlockEvents())
+	if err != nil {
+		return nil, fmt.Errorf("Error getting event service: %v", err)
+	}
+	registration, statusChan, err := eventService.RegisterTxStatusEvent(txID)
+	if err != nil {
+		return nil, fmt.Errorf("Error registering for tx status event of %s: %v", txID, err)
+	}
+	defer eventService.Unregister(registration)
+
+	status, lookupErr := fsc.lookupTxStatus(channelID, txID)
+	if lookupErr == nil {
+		return status, nil
+	}
+	_logger.Debugf("Transaction %s not in the ledger yet, waiting for its status event: %v", txID, lookupErr)
+	select {
+	case event, ok := <-statusChan:
+		if !ok {
+			return nil, fmt.Errorf("Tx status event channel closed while waiting for %s", txID)
+		}
+		return newTxStatus(event.TxID, int32(event.TxValidationCode), event.BlockNumber), nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("Stopped waiting for transaction %s: %v", txID, ctx.Err())
+	}
+}
+
+//lookupTxStatus returns the status of a transaction already in the ledger
+func (fsc *FabricSDKClient) lookupTxStatus(channelID, txID string) (*TxStatus, error) {
+	ledgerClient, err := fsc.getLedgerClient(channelID)
+	if err != nil {
+		return nil, err
+	}
+	processedTx, err := ledgerClient.QueryTransaction(fab.TransactionID(txID))
+	if err != nil {
+		return nil, err
+	}
+	block, err := ledgerClient.QueryBlockByTxID(fab.TransactionID(txID))
+	if err != nil {
+		return nil, err
+	}
+	return newTxStatus(txID, processedTx.ValidationCode, block.GetHeader().GetNumber()), nil
+}
@@ -0,0 +1,446 @@
+package fabricgosdkclientcore
+
+import (
+	"fmt"
+
+	mspclient "github.com/hyperledger/fabric-sdk-go/pkg/client/msp"
+)
+
+//Identity types known to the Fabric CA
+const (
+	IdentityTypeUser   = "user"
+	IdentityTypeClient = "client"
+	IdentityTypePeer   = "peer"
+	IdentityTypeAdmin  = "admin"
+)
+
+//UnlimitedEnrollments lets a registered identity enroll any number of times. A max enrollments
+//of 0 takes the default of the CA.
+const UnlimitedEnrollments = -1
+
+//UserAttribute is an attribute of a registered identity. The attributes with ECert set are
+//added to the enrollment certificate by default.
+type UserAttribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	ECert bool   `json:"ecert"`
+}
+
+//UserRegistration is the registration request of a new identity. Type defaults to user and
+//the CA generates the secret when it is empty.
+type UserRegistration struct {
+	UserID         string          `json:"userId"`
+	Secret         string          `json:"secret,omitempty"`
+	Type           string          `json:"type"`
+	Affiliation    string          `json:"affiliation"`
+	Attributes     []UserAttribute `json:"attributes,omitempty"`
+	MaxEnrollments int             `json:"maxEnrollments"`
+	CAName         string          `json:"caName,omitempty"`
+}
+
+//AttributeRequest asks for an attribute in the enrollment certificate. The enrollment fails
+//when a required attribute is not registered for the identity.
+type AttributeRequest struct {
+	Name     string `json:"name"`
+	Optional bool   `json:"optional"`
+}
+
+//EnrollmentOptions are the optional settings of an enrollment. When AttributeRequests is set
+//only the requested attributes are added to the certificate instead of the ECert attributes.
+type EnrollmentOptions struct {
+	AttributeRequests []AttributeRequest `json:"attributeRequests,omitempty"`
+	Profile           string             `json:"profile,omitempty"`
+	Label             string             `json:"label,omitempty"`
+	Type              string             `json:"type,omitempty"`
+}
+
+func toMSPAttributes(attributes []UserAttribute) []mspclient.Attribute {
+	mspAttributes := make([]mspclient.Attribute, 0, len(attributes))
+	for _, attribute := range attributes {
+		mspAttributes = append(mspAttributes, mspclient.Attribute{Name: attribute.Name, Value: attribute.Value, ECert: attribute.ECert})
+	}
+	return mspAttributes
+}
+
+func fromMSPAttributes(mspAttributes []mspclient.Attribute) []UserAttribute {
+	attributes := make([]UserAttribute, 0, len(mspAttributes))
+	for _, attribute := range mspAttributes {
+		attributes = append(attributes, UserAttribute{Name: attribute.Name, Value: attribute.Value, ECert: attribute.ECert})
+	}
+	return attributes
+}
+
+//enrollmentOptions converts the options to the msp client options, the secret first
+func (options *EnrollmentOptions) enrollmentOptions(secret string) []mspclient.EnrollmentOption {
+	enrollOptions := make([]mspclient.EnrollmentOption, 0)
+	if len(secret) > 0 {
+		enrollOptions = append(enrollOptions, mspclient.WithSecret(secret))
+	}
+	if options == nil {
+		return enrollOptions
+	}
+	if len(options.AttributeRequests) > 0 {
+		attrRequests := make([]*mspclient.AttributeRequest, 0, len(options.AttributeRequests))
+		for _, attrRequest := range options.AttributeRequests {
+			attrRequests = append(attrRequests, &mspclient.AttributeRequest{Name: attrRequest.Name, Optional: attrRequest.Optional})
+		}
+		enrollOptions = append(enrollOptions, mspclient.WithAttributeRequests(attrRequests))
+	}
+	if len(options.Profile) > 0 {
+		enrollOptions = append(enrollOptions, mspclient.WithProfile(options.Profile))
+	}
+	if len(options.Label) > 0 {
+		enrollOptions = append(enrollOptions, mspclient.WithLabel(options.Label))
+	}
+	if len(options.Type) > 0 {
+		enrollOptions = append(enrollOptions, mspclient.WithType(options.Type))
+	}
+	return enrollOptions
+}
+
+//RegisterUser registers a new identity with the CA of the organization using the registrar
+//enrolled by EnrollOrgAdmin and returns the enrollment secret. The affiliation is checked
+//against the affiliation tree of the CA first.
+func (fsc *FabricSDKClient) RegisterUser(registration *UserRegistration) (string, error) {
+	if len(registration.UserID) == 0 {
+		return "", fmt.Errorf("User ID is required for registration")
+	}
+	if len(registration.Affiliation) > 0 {
+		//A registrar without the right to list the affiliations leaves the check to the CA
+		isFound, err := fsc.AffiliationExists(registration.Affiliation, registration.CAName)
+		if err != nil {
+			_logger.Warningf("Affiliation %s of %s not validated: %v", registration.Affiliation, registration.UserID, err)
+		} else if !isFound {
+			return "", fmt.Errorf("Affiliation %s of %s does not exist", registration.Affiliation, registration.UserID)
+		}
+	}
+	identityType := registration.Type
+	if len(identityType) == 0 {
+		identityType = IdentityTypeUser
+	}
+	var secret string
+	err := fsc.withRegistrar(func() (err error) {
+		secret, err = fsc.getMSPClient().Register(&mspclient.RegistrationRequest{
+			Name:           registration.UserID,
+			Type:           identityType,
+			MaxEnrollments: registration.MaxEnrollments,
+			Affiliation:    registration.Affiliation,
+			Attributes:     toMSPAttributes(registration.Attributes),
+			CAName:         registration.CAName,
+			Secret:         registration.Secret,
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Registration of %s failed: %v", registration.UserID, err)
+	}
+	return secret, nil
+}
+
+//EnrollUser enrolls a registered identity and stores its certificate in the credential store.
+//options may be nil.
+func (fsc *FabricSDKClient) EnrollUser(uid, secret string, options *EnrollmentOptions) error {
+	if err := fsc.getMSPClient().Enroll(uid, options.enrollmentOptions(secret)...); err != nil {
+		return fmt.Errorf("Enrollment of %s failed: %v", uid, err)
+	}
+	if _, err := fsc.getMSPClient().GetSigningIdentity(uid); err != nil {
+		return fmt.Errorf("Unable to get the signing identity of %s: %v", uid, err)
+	}
+	fsc.addEnrolledUser(uid)
+	return fsc.storeInWallet(uid)
+}
+
+//RegisterAndEnrollUser registers a new identity and enrolls it with the secret returned by the CA
+func (fsc *FabricSDKClient) RegisterAndEnrollUser(registration *UserRegistration, options *EnrollmentOptions) error {
+	secret, err := fsc.RegisterUser(registration)
+	if err != nil {
+		return err
+	}
+	return fsc.EnrollUser(registration.UserID, secret, options)
+}
+
+//Revocation reasons accepted by the Fabric CA
+const (
+	RevocationReasonUnspecified        = "unspecified"
+	RevocationReasonKeyCompromise      = "keycompromise"
+	RevocationReasonAffiliationChange  = "affiliationchange"
+	RevocationReasonSuperseded         = "superseded"
+	RevocationReasonCessation          = "cessationofoperation"
+	RevocationReasonPrivilegeWithdrawn = "privilegewithdrawn"
+)
+
+//Identity is an identity registered with the CA
+type Identity struct {
+	UserID         string          `json:"userId"`
+	Type           string          `json:"type"`
+	Affiliation    string          `json:"affiliation"`
+	Attributes     []UserAttribute `json:"attributes"`
+	MaxEnrollments int             `json:"maxEnrollments"`
+	CAName         string          `json:"caName,omitempty"`
+}
+
+//UserRevocation revokes all the certificates of an identity when UserID is set, otherwise the
+//certificate with the serial number and authority key identifier, both hex encoded
+type UserRevocation struct {
+	UserID string `json:"userId,omitempty"`
+	Serial string `json:"serial,omitempty"`
+	AKI    string `json:"aki,omitempty"`
+	Reason string `json:"reason"`
+	CAName string `json:"caName,omitempty"`
+	GenCRL bool   `json:"genCRL"`
+}
+
+//RevokedCert is a certificate revoked by the CA
+type RevokedCert struct {
+	Serial string `json:"serial"`
+	AKI    string `json:"aki"`
+}
+
+//RevocationResult lists the revoked certificates with the PEM encoded CRL of the CA when asked for
+type RevocationResult struct {
+	RevokedCerts []RevokedCert `json:"revokedCerts"`
+	CRL          []byte        `json:"crl,omitempty"`
+}
+
+func newIdentity(response *mspclient.IdentityResponse) *Identity {
+	return &Identity{
+		UserID:         response.ID,
+		Type:           response.Type,
+		Affiliation:    response.Affiliation,
+		Attributes:     fromMSPAttributes(response.Attributes),
+		MaxEnrollments: response.MaxEnrollments,
+		CAName:         response.CAName,
+	}
+}
+
+func caRequestOptions(caName string) []mspclient.RequestOption {
+	if len(caName) == 0 {
+		return nil
+	}
+	return []mspclient.RequestOption{mspclient.WithCA(caName)}
+}
+
+//ReenrollUser renews the enrollment certificate of an enrolled identity. options may be nil.
+func (fsc *FabricSDKClient) ReenrollUser(uid string, options *EnrollmentOptions) error {
+	err := fsc.withStoreIdentity(uid, func() error {
+		return fsc.getMSPClient().Reenroll(uid, options.enrollmentOptions("")...)
+	})
+	if err != nil {
+		return fmt.Errorf("Reenrollment of %s failed: %v", uid, err)
+	}
+	return nil
+}
+
+//RevokeUser revokes the certificates of an identity, or a single certificate, and generates the
+//CRL when GenCRL is set
+func (fsc *FabricSDKClient) RevokeUser(revocation *UserRevocation) (*RevocationResult, error) {
+	if len(revocation.UserID) == 0 && (len(revocation.Serial) == 0 || len(revocation.AKI) == 0) {
+		return nil, fmt.Errorf("User ID or certificate serial and AKI are required for revocation")
+	}
+	var response *mspclient.RevocationResponse
+	err := fsc.withRegistrar(func() (err error) {
+		response, err = fsc.getMSPClient().Revoke(&mspclient.RevocationRequest{
+			Name:   revocation.UserID,
+			Serial: revocation.Serial,
+			AKI:    revocation.AKI,
+			Reason: revocation.Reason,
+			CAName: revocation.CAName,
+			GenCRL: revocation.GenCRL,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Revocation failed: %v", err)
+	}
+	result := &RevocationResult{RevokedCerts: make([]RevokedCert, 0, len(response.RevokedCerts)), CRL: response.CRL}
+	for _, revokedCert := range response.RevokedCerts {
+		result.RevokedCerts = append(result.RevokedCerts, RevokedCert{Serial: revokedCert.Serial, AKI: revokedCert.AKI})
+	}
+	return result, nil
+}
+
+//ListIdentities returns the identities the registrar is allowed to see. caName may be empty for
+//the default CA.
+func (fsc *FabricSDKClient) ListIdentities(caName string) ([]*Identity, error) {
+	var responses []*mspclient.IdentityResponse
+	err := fsc.withRegistrar(func() (err error) {
+		responses, err = fsc.getMSPClient().GetAllIdentities(caRequestOptions(caName)...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list identities: %v", err)
+	}
+	identities := make([]*Identity, 0, len(responses))
+	for _, response := range responses {
+		identities = append(identities, newIdentity(response))
+	}
+	return identities, nil
+}
+
+//GetIdentity returns a registered identity
+func (fsc *FabricSDKClient) GetIdentity(uid, caName string) (*Identity, error) {
+	var response *mspclient.IdentityResponse
+	err := fsc.withRegistrar(func() (err error) {
+		response, err = fsc.getMSPClient().GetIdentity(uid, caRequestOptions(caName)...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get identity %s: %v", uid, err)
+	}
+	return newIdentity(response), nil
+}
+
+//ModifyIdentity updates the type, affiliation, attributes, max enrollments or secret of a
+//registered identity. The attributes given replace the attributes of the same name.
+func (fsc *FabricSDKClient) ModifyIdentity(update *UserRegistration) (*Identity, error) {
+	var response *mspclient.IdentityResponse
+	err := fsc.withRegistrar(func() (err error) {
+		response, err = fsc.getMSPClient().ModifyIdentity(&mspclient.IdentityRequest{
+			ID:             update.UserID,
+			Affiliation:    update.Affiliation,
+			Attributes:     toMSPAttributes(update.Attributes),
+			Type:           update.Type,
+			MaxEnrollments: update.MaxEnrollments,
+			Secret:         update.Secret,
+			CAName:         update.CAName,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to modify identity %s: %v", update.UserID, err)
+	}
+	return newIdentity(response), nil
+}
+
+//RemoveIdentity removes an identity from the CA, force allows the registrar to remove itself.
+//The CA must run with identity removal enabled.
+func (fsc *FabricSDKClient) RemoveIdentity(uid string, force bool, caName string) (*Identity, error) {
+	var response *mspclient.IdentityResponse
+	err := fsc.withRegistrar(func() (err error) {
+		response, err = fsc.getMSPClient().RemoveIdentity(&mspclient.RemoveIdentityRequest{ID: uid, Force: force, CAName: caName})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to remove identity %s: %v", uid, err)
+	}
+	return newIdentity(response), nil
+}
+
+//Affiliation is a node of the affiliation tree of the CA with the identities directly under it.
+//Name is the full dotted name of the affiliation.
+type Affiliation struct {
+	Name         string         `json:"name"`
+	Affiliations []*Affiliation `json:"affiliations,omitempty"`
+	Identities   []*Identity    `json:"identities,omitempty"`
+	CAName       string         `json:"caName,omitempty"`
+}
+
+func newAffiliation(info mspclient.AffiliationInfo, caName string) *Affiliation {
+	affiliation := &Affiliation{Name: info.Name, CAName: caName}
+	for _, child := range info.Affiliations {
+		affiliation.Affiliations = append(affiliation.Affiliations, newAffiliation(child, caName))
+	}
+	for _, identity := range info.Identities {
+		affiliation.Identities = append(affiliation.Identities, &Identity{
+			UserID:         identity.ID,
+			Type:           identity.Type,
+			Affiliation:    identity.Affiliation,
+			Attributes:     fromMSPAttributes(identity.Attributes),
+			MaxEnrollments: identity.MaxEnrollments,
+			CAName:         caName,
+		})
+	}
+	return affiliation
+}
+
+//Find returns the affiliation of the tree with the full name, nil if not found
+func (affiliation *Affiliation) Find(name string) *Affiliation {
+	if affiliation.Name == name {
+		return affiliation
+	}
+	for _, child := range affiliation.Affiliations {
+		if found := child.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+//ListAffiliations returns the affiliation tree the registrar is allowed to see
+func (fsc *FabricSDKClient) ListAffiliations(caName string) (*Affiliation, error) {
+	var response *mspclient.AffiliationResponse
+	err := fsc.withRegistrar(func() (err error) {
+		response, err = fsc.getMSPClient().GetAllAffiliations(caRequestOptions(caName)...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list affiliations: %v", err)
+	}
+	return newAffiliation(response.AffiliationInfo, response.CAName), nil
+}
+
+//GetAffiliation returns an affiliation with the affiliations and identities under it
+func (fsc *FabricSDKClient) GetAffiliation(name, caName string) (*Affiliation, error) {
+	var response *mspclient.AffiliationResponse
+	err := fsc.withRegistrar(func() (err error) {
+		response, err = fsc.getMSPClient().GetAffiliation(name, caRequestOptions(caName)...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get affiliation %s: %v", name, err)
+	}
+	return newAffiliation(response.AffiliationInfo, response.CAName), nil
+}
+
+//AddAffiliation adds an affiliation, force creates the missing parent affiliations
+func (fsc *FabricSDKClient) AddAffiliation(name string, force bool, caName string) (*Affiliation, error) {
+	var response *mspclient.AffiliationResponse
+	err := fsc.withRegistrar(func() (err error) {
+		response, err = fsc.getMSPClient().AddAffiliation(&mspclient.AffiliationRequest{Name: name, Force: force, CAName: caName})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to add affiliation %s: %v", name, err)
+	}
+	return newAffiliation(response.AffiliationInfo, response.CAName), nil
+}
+
+//ModifyAffiliation renames an affiliation, force also moves the identities of the affiliation
+//and of the affiliations under it
+func (fsc *FabricSDKClient) ModifyAffiliation(name, newName string, force bool, caName string) (*Affiliation, error) {
+	var response *mspclient.AffiliationResponse
+	err := fsc.withRegistrar(func() (err error) {
+		response, err = fsc.getMSPClient().ModifyAffiliation(&mspclient.ModifyAffiliationRequest{
+			NewName:            newName,
+			AffiliationRequest: mspclient.AffiliationRequest{Name: name, Force: force, CAName: caName},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to rename affiliation %s to %s: %v", name, newName, err)
+	}
+	return newAffiliation(response.AffiliationInfo, response.CAName), nil
+}
+
+//RemoveAffiliation removes an affiliation, force also removes the affiliations and identities
+//under it. The CA must run with affiliation removal enabled.
+func (fsc *FabricSDKClient) RemoveAffiliation(name string, force bool, caName string) (*Affiliation, error) {
+	var response *mspclient.AffiliationResponse
+	err := fsc.withRegistrar(func() (err error) {
+		response, err = fsc.getMSPClient().RemoveAffiliation(&mspclient.AffiliationRequest{Name: name, Force: force, CAName: caName})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to remove affiliation %s: %v", name, err)
+	}
+	return newAffiliation(response.AffiliationInfo, response.CAName), nil
+}
+
+//AffiliationExists checks the affiliation is in the affiliation tree of the CA
+func (fsc *FabricSDKClient) AffiliationExists(name, caName string) (bool, error) {
+	tree, err := fsc.ListAffiliations(caName)
+	if err != nil {
+		return false, err
+	}
+	return tree.Find(name) != nil, nil
+}
@@ -0,0 +1,504 @@
+package fabricgosdkclientcore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	msp "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/msp"
+	fabsdk "github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	"golang.org/x/crypto/scrypt"
+)
+
+//ErrIdentityNotFound is returned by the wallets for an unknown user
+var ErrIdentityNotFound = errors.New("Identity not found in the wallet")
+
+//Wallet types of the x-wallet configuration
+const (
+	WalletTypeMemory    = "memory"
+	WalletTypeFile      = "file"
+	WalletTypeEncrypted = "encrypted"
+)
+
+//Wallet stores the certificates and private keys of the identities used by the client
+type Wallet interface {
+	Put(identity *StoredIdentity) error
+	Get(userID string) (*StoredIdentity, error)
+	List() ([]string, error)
+	Remove(userID string) error
+}
+
+//WalletConfig is the x-wallet configuration. The file wallet uses the credential and key stores
+//of the SDK configuration. The encrypted wallet stores the identities in Path encrypted with a
+//key derived from the content of KeyFile.
+type WalletConfig struct {
+	Type    string `json:"type"`
+	Path    string `json:"path"`
+	KeyFile string `json:"keyFile"`
+}
+
+//InMemoryWallet keeps the identities in memory
+type InMemoryWallet struct {
+	identities map[string]*StoredIdentity
+	lock       sync.RWMutex
+}
+
+//NewInMemoryWallet creates an empty in-memory wallet
+func NewInMemoryWallet() *InMemoryWallet {
+	return &InMemoryWallet{identities: make(map[string]*StoredIdentity)}
+}
+
+//Put adds or replaces an identity
+func (imw *InMemoryWallet) Put(identity *StoredIdentity) error {
+	if _, err := ValidateCertKeyPair(identity.CertPEM, identity.KeyPEM); err != nil {
+		return err
+	}
+	imw.lock.Lock()
+	defer imw.lock.Unlock()
+	stored := *identity
+	imw.identities[identity.UserID] = &stored
+	return nil
+}
+
+//Get returns an identity
+func (imw *InMemoryWallet) Get(userID string) (*StoredIdentity, error) {
+	imw.lock.RLock()
+	defer imw.lock.RUnlock()
+	identity, isFound := imw.identities[userID]
+	if !isFound {
+		return nil, ErrIdentityNotFound
+	}
+	stored := *identity
+	return &stored, nil
+}
+
+//List returns the user IDs of the identities, sorted
+func (imw *InMemoryWallet) List() ([]string, error) {
+	imw.lock.RLock()
+	defer imw.lock.RUnlock()
+	userIDs := make([]string, 0, len(imw.identities))
+	for userID := range imw.identities {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+//Remove removes an identity
+func (imw *InMemoryWallet) Remove(userID string) error {
+	imw.lock.Lock()
+	defer imw.lock.Unlock()
+	if _, isFound := imw.identities[userID]; !isFound {
+		return ErrIdentityNotFound
+	}
+	delete(imw.identities, userID)
+	return nil
+}
+
+//FileWallet keeps the identities in the credential store and key store layout of the SDK
+type FileWallet struct {
+	credentialStorePath string
+	keyStorePath        string
+}
+
+//NewFileWallet creates a wallet over a credential store and a key store
+func NewFileWallet(credentialStorePath, keyStorePath string) *FileWallet {
+	return &FileWallet{credentialStorePath: credentialStorePath, keyStorePath: keyStorePath}
+}
+
+//Put adds or replaces an identity
+func (fw *FileWallet) Put(identity *StoredIdentity) error {
+	return WriteIdentityFiles(fw.credentialStorePath, fw.keyStorePath, identity)
+}
+
+//certFiles returns the certificate files of the credential store by user ID
+func (fw *FileWallet) certFiles() (map[string]string, error) {
+	certPaths, err := filepath.Glob(filepath.Join(fw.credentialStorePath, "*@*-cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	mspIDs := make(map[string]string)
+	for _, certPath := range certPaths {
+		name := strings.TrimSuffix(filepath.Base(certPath), "-cert.pem")
+		separator := strings.LastIndex(name, "@")
+		mspIDs[name[:separator]] = name[separator+1:]
+	}
+	return mspIDs, nil
+}
+
+//Get returns an identity
+func (fw *FileWallet) Get(userID string) (*StoredIdentity, error) {
+	mspIDs, err := fw.certFiles()
+	if err != nil {
+		return nil, err
+	}
+	mspID, isFound := mspIDs[userID]
+	if !isFound {
+		return nil, ErrIdentityNotFound
+	}
+	return ReadIdentityFiles(fw.credentialStorePath, fw.keyStorePath, userID, mspID)
+}
+
+//List returns the user IDs of the identities, sorted
+func (fw *FileWallet) List() ([]string, error) {
+	mspIDs, err := fw.certFiles()
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]string, 0, len(mspIDs))
+	for userID := range mspIDs {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+//Remove deletes the certificate and the private key of an identity
+func (fw *FileWallet) Remove(userID string) error {
+	identity, err := fw.Get(userID)
+	if err != nil {
+		return err
+	}
+	return removeIdentityFiles(fw.credentialStorePath, fw.keyStorePath, identity)
+}
+
+func removeIdentityFiles(credentialStorePath, keyStorePath string, identity *StoredIdentity) error {
+	ski, err := ValidateCertKeyPair(identity.CertPEM, identity.KeyPEM)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(keyStorePath, ski+"_sk")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to remove the private key of %s: %v", identity.UserID, err)
+	}
+	if err := os.Remove(filepath.Join(credentialStorePath, identityCertFileName(identity.UserID, identity.MSPID))); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to remove the certificate of %s: %v", identity.UserID, err)
+	}
+	return nil
+}
+
+//EncryptedFileWallet keeps each identity in a file encrypted with AES-256-GCM. The key is derived
+//with scrypt from a passphrase and the random salt stored with the wallet.
+type EncryptedFileWallet struct {
+	dir  string
+	aead cipher.AEAD
+	lock sync.Mutex
+}
+
+const (
+	walletSaltFileName     = "wallet.salt"
+	walletIdentityFileType = ".id"
+)
+
+//NewEncryptedFileWallet opens or creates an encrypted wallet in dir. A wrong passphrase is
+//reported when an identity is read.
+func NewEncryptedFileWallet(dir string, passphrase []byte) (*EncryptedFileWallet, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("Passphrase of the wallet %s is empty", dir)
+	}
+	saltPath := filepath.Join(dir, walletSaltFileName)
+	salt, err := ioutil.ReadFile(saltPath)
+	if os.IsNotExist(err) {
+		salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+		err = writeFileAtomicMode(saltPath, salt, 0600)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the salt of the wallet %s: %v", dir, err)
+	}
+	key, err := scrypt.Key(passphrase, salt, 32768, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedFileWallet{dir: dir, aead: aead}, nil
+}
+
+//NewEncryptedFileWalletFromKeyFile opens or creates an encrypted wallet with the content of a key
+//file as passphrase
+func NewEncryptedFileWalletFromKeyFile(dir, keyFile string) (*EncryptedFileWallet, error) {
+	passphrase, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the wallet key file %s: %v", keyFile, err)
+	}
+	return NewEncryptedFileWallet(dir, passphrase)
+}
+
+//identityPath is the file of an identity, the user ID is hex encoded to be a safe file name
+func (efw *EncryptedFileWallet) identityPath(userID string) string {
+	return filepath.Join(efw.dir, fmt.Sprintf("%x%s", userID, walletIdentityFileType))
+}
+
+//Put adds or replaces an identity
+func (efw *EncryptedFileWallet) Put(identity *StoredIdentity) error {
+	if _, err := ValidateCertKeyPair(identity.CertPEM, identity.KeyPEM); err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(identity)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, efw.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	//The user ID is authenticated so that a file can not be renamed to another user
+	ciphertext := efw.aead.Seal(nonce, nonce, plaintext, []byte(identity.UserID))
+	efw.lock.Lock()
+	defer efw.lock.Unlock()
+	return writeFileAtomicMode(efw.identityPath(identity.UserID), ciphertext, 0600)
+}
+
+//Get decrypts an identity
+func (efw *EncryptedFileWallet) Get(userID string) (*StoredIdentity, error) {
+	efw.lock.Lock()
+	ciphertext, err := ioutil.ReadFile(efw.identityPath(userID))
+	efw.lock.Unlock()
+	if os.IsNotExist(err) {
+		return nil, ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := efw.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("Identity file of %s is truncated", userID)
+	}
+	plaintext, err := efw.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], []byte(userID))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt the identity of %s, wrong passphrase or corrupted file", userID)
+	}
+	identity := new(StoredIdentity)
+	if err := json.Unmarshal(plaintext, identity); err != nil {
+		return nil, fmt.Errorf("Invalid identity of %s: %v", userID, err)
+	}
+	return identity, nil
+}
+
+//List returns the user IDs of the identities, sorted
+func (efw *EncryptedFileWallet) List() ([]string, error) {
+	identityPaths, err := filepath.Glob(filepath.Join(efw.dir, "*"+walletIdentityFileType))
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]string, 0, len(identityPaths))
+	for _, identityPath := range identityPaths {
+		var userID string
+		if _, err := fmt.Sscanf(strings.TrimSuffix(filepath.Base(identityPath), walletIdentityFileType), "%x", &userID); err == nil {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+//Remove deletes an identity
+func (efw *EncryptedFileWallet) Remove(userID string) error {
+	efw.lock.Lock()
+	defer efw.lock.Unlock()
+	err := os.Remove(efw.identityPath(userID))
+	if os.IsNotExist(err) {
+		return ErrIdentityNotFound
+	}
+	return err
+}
+
+//newWallet creates the wallet of the x-wallet configuration
+func (fsc *FabricSDKClient) newWallet(config WalletConfig) (Wallet, error) {
+	switch config.Type {
+	case WalletTypeMemory:
+		return NewInMemoryWallet(), nil
+	case WalletTypeFile:
+		credentialStorePath, keyStorePath, err := fsc.identityStorePaths()
+		if err != nil {
+			return nil, err
+		}
+		return NewFileWallet(credentialStorePath, keyStorePath), nil
+	case WalletTypeEncrypted:
+		wallet, err := NewEncryptedFileWalletFromKeyFile(config.Path, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return wallet, nil
+	}
+	return nil, fmt.Errorf("Unknown wallet type %s", config.Type)
+}
+
+//SetWallet makes the client sign with the identities of the wallet. The users enrolled afterwards
+//are stored in the wallet and, unless it is a file wallet, removed from the SDK stores.
+func (fsc *FabricSDKClient) SetWallet(wallet Wallet) {
+	fsc.clientsLock.Lock()
+	defer fsc.clientsLock.Unlock()
+	fsc.wallet = wallet
+}
+
+//Wallet returns the wallet of the client, nil if the SDK stores are used directly
+func (fsc *FabricSDKClient) Wallet() Wallet {
+	fsc.clientsLock.RLock()
+	defer fsc.clientsLock.RUnlock()
+	return fsc.wallet
+}
+
+//walletIdentity returns the signing identity of a user of the wallet, nil if the user is not in
+//the wallet
+func (fsc *FabricSDKClient) walletIdentity(userID string) (msp.SigningIdentity, error) {
+	wallet := fsc.Wallet()
+	if wallet == nil {
+		return nil, nil
+	}
+	identity, err := wallet.Get(userID)
+	if err == ErrIdentityNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	signingIdentity, err := fsc.getMSPClient().CreateSigningIdentity(msp.WithCert(identity.CertPEM), msp.WithPrivateKey(identity.KeyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create the signing identity of %s from the wallet: %v", userID, err)
+	}
+	return signingIdentity, nil
+}
+
+//identityOption selects the user of a context: an external signer identity, an identity of the
+//wallet or a user of the SDK stores
+func (fsc *FabricSDKClient) identityOption(userID string) (fabsdk.ContextOption, error) {
+	if externalIdentity := fsc.externalIdentity(userID); externalIdentity != nil {
+		return fabsdk.WithIdentity(externalIdentity), nil
+	}
+	signingIdentity, err := fsc.walletIdentity(userID)
+	if err != nil {
+		return nil, err
+	}
+	if signingIdentity != nil {
+		return fabsdk.WithIdentity(signingIdentity), nil
+	}
+	return fabsdk.WithUser(userID), nil
+}
+
+//storeInWallet moves an enrolled user from the SDK stores to the wallet
+func (fsc *FabricSDKClient) storeInWallet(userID string) error {
+	wallet := fsc.Wallet()
+	if wallet == nil {
+		return nil
+	}
+	identity, err := fsc.readStoreIdentity(userID)
+	if err != nil {
+		return err
+	}
+	if err := wallet.Put(identity); err != nil {
+		return fmt.Errorf("Unable to store %s in the wallet: %v", userID, err)
+	}
+	if _, isFileWallet := wallet.(*FileWallet); isFileWallet {
+		return nil
+	}
+	return fsc.removeStoreIdentity(identity)
+}
+
+//restoreFromWallet writes a user of the wallet back in the SDK stores for the CA requests signed
+//with its enrollment. It returns the identity written, nil when the user is not in a wallet apart
+//from the SDK stores.
+func (fsc *FabricSDKClient) restoreFromWallet(userID string) (*StoredIdentity, error) {
+	wallet := fsc.Wallet()
+	if _, isFileWallet := wallet.(*FileWallet); wallet == nil || isFileWallet {
+		return nil, nil
+	}
+	identity, err := wallet.Get(userID)
+	if err == ErrIdentityNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	credentialStorePath, keyStorePath, err := fsc.identityStorePaths()
+	if err != nil {
+		return nil, err
+	}
+	if err := WriteIdentityFiles(credentialStorePath, keyStorePath, identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+//withStoreIdentity runs a CA request signed with the enrollment of a user of the wallet. The user
+//is written back to the SDK stores for the request, then its new enrollment, or the old one when
+//the request failed, goes back to the wallet. The files of the restored enrollment are removed in
+//any case, a reenrollment leaves the old key in the key store otherwise. The requests run one at
+//a time so that a user is not removed from the stores while another request signs with it.
+func (fsc *FabricSDKClient) withStoreIdentity(userID string, request func() error) (err error) {
+	fsc.storeIdentityLock.Lock()
+	defer fsc.storeIdentityLock.Unlock()
+	restoredIdentity, err := fsc.restoreFromWallet(userID)
+	if err != nil {
+		return err
+	}
+	if restoredIdentity != nil {
+		defer func() {
+			if cleanupErr := fsc.removeStoreIdentity(restoredIdentity); cleanupErr != nil && err == nil {
+				err = cleanupErr
+			}
+		}()
+	}
+	err = request()
+	if restoredIdentity != nil {
+		if walletErr := fsc.storeInWallet(userID); walletErr != nil && err == nil {
+			err = walletErr
+		}
+	}
+	return err
+}
+
+//removeStoreIdentity removes the certificate and private key of an identity from the SDK stores
+func (fsc *FabricSDKClient) removeStoreIdentity(identity *StoredIdentity) error {
+	credentialStorePath, keyStorePath, err := fsc.identityStorePaths()
+	if err != nil {
+		return err
+	}
+	return removeIdentityFiles(credentialStorePath, keyStorePath, identity)
+}
+
+//withRegistrar runs a CA request signed by the registrar, the org admin enrolled from the
+//configuration
+func (fsc *FabricSDKClient) withRegistrar(request func() error) error {
+	return fsc.withStoreIdentity(fsc.orgAdmin, request)
+}
+
+//userCertificate returns the enrollment certificate of an external signer user, of a user of the
+//wallet or of a user of the SDK stores
+func (fsc *FabricSDKClient) userCertificate(userID string) ([]byte, error) {
+	if externalIdentity := fsc.externalIdentity(userID); externalIdentity != nil {
+		return externalIdentity.EnrollmentCertificate(), nil
+	}
+	if wallet := fsc.Wallet(); wallet != nil {
+		identity, err := wallet.Get(userID)
+		if err == nil {
+			return identity.CertPEM, nil
+		}
+		if err != ErrIdentityNotFound {
+			return nil, err
+		}
+	}
+	signingIdentity, err := fsc.getMSPClient().GetSigningIdentity(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the signing identity of %s: %v", userID, err)
+	}
+	return signingIdentity.EnrollmentCertificate(), nil
+}